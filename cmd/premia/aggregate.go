@@ -0,0 +1,113 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/premia-ai/cli/internal/aggregates"
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	aggregateInstrument string
+	aggregateTables     []string
+	aggregateFrom       string
+	aggregateTo         string
+)
+
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate",
+	Short: "Manage the aggregates of your base tables",
+}
+
+var aggregateRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Refresh aggregates for a time range, e.g. after a backfill",
+	Long: `Refresh aggregates for a time range, e.g. after a backfill. With the
+postgres backend aggregates are materialized views which are always refreshed
+completely, so --from and --to are ignored. Run this command regularly, e.g.
+with cron, to keep them up to date. Rows of the base table that ended up in
+its default partition are moved into yearly partitions first. With the duckdb backend aggregates are
+views that are always up to date.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		instrumentConfig, ok := configData.Instruments[config.InstrumentType(aggregateInstrument)]
+		if !ok {
+			log.Fatalf("There is no '%s' table set up.", aggregateInstrument)
+		}
+
+		tables := aggregateTables
+		if len(tables) == 0 {
+			for _, aggregate := range instrumentConfig.Aggregates {
+				tables = append(tables, aggregate.Table)
+			}
+		}
+		if len(tables) == 0 {
+			log.Fatalf("There are no aggregates of '%s' set up.", instrumentConfig.BaseTable)
+		}
+
+		switch configData.DatabaseBackend() {
+		case config.BackendDuckDB:
+			fmt.Println("Aggregates of the duckdb backend are views that are always up to date, there is nothing to refresh.")
+			return
+		case config.BackendPostgres:
+			db, err := database.Open()
+			if err != nil {
+				log.Fatal(err)
+			}
+			// Imports move their rows out of the default partition, this
+			// also moves the rows of imports from before
+			err = database.MaintainPartitions(db, instrumentConfig.BaseTable)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = aggregates.RefreshViews(tables)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println("Successfully refreshed aggregates!")
+			return
+		}
+
+		if aggregateFrom == "" {
+			log.Fatal("Please provide the start of the refreshed range with --from")
+		}
+		fromTime, err := time.Parse(time.RFC3339, aggregateFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		toTime := time.Now()
+		if aggregateTo != "" {
+			toTime, err = time.Parse(time.RFC3339, aggregateTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		err = aggregates.Refresh(tables, fromTime, toTime)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully refreshed aggregates!")
+	},
+}
+
+func init() {
+	aggregateRefreshCmd.Flags().StringVar(&aggregateInstrument, "instrument", string(config.Stocks), "Instrument type whose aggregates are refreshed")
+	aggregateRefreshCmd.Flags().StringSliceVar(&aggregateTables, "tables", nil, "Only refresh these aggregates (separate values by ,)")
+	aggregateRefreshCmd.Flags().StringVar(&aggregateFrom, "from", "", "Start of the refreshed range in RFC3339 format (required with TimescaleDB)")
+	aggregateRefreshCmd.Flags().StringVar(&aggregateTo, "to", "", "End of the refreshed range in RFC3339 format (default now)")
+	aggregateCmd.AddCommand(aggregateRefreshCmd)
+	rootCmd.AddCommand(aggregateCmd)
+}
@@ -0,0 +1,104 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/credentials"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage API keys and database passwords",
+	Long: `Manage API keys and database passwords. Credentials are stored encrypted
+in ~/.premia/credentials, which is only readable by you. The key is kept in the
+OS keyring, or in ~/.premia/credentials.key on systems without one.
+Environment variables like POLYGON_API_KEY take precedence over stored
+credentials. The postgres password is stored per profile, use --profile to
+manage the one of another profile than the active one.`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:       "set <name>",
+	Short:     "Store a credential, the value is read without echoing it",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: credentials.Names(),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := credentials.ReadSecret(
+			fmt.Sprintf("What is the value of '%s'?", args[0]),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if value == "" {
+			log.Fatal("The value must not be empty.")
+		}
+
+		err = credentials.Set(profileCredential(args[0]), value)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Successfully stored '%s'!\n", args[0])
+	},
+}
+
+var authListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available credentials without their values",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		availableCredentials, err := credentials.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(availableCredentials) == 0 {
+			fmt.Println("No credentials are stored.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE")
+		for _, credential := range availableCredentials {
+			fmt.Fprintf(w, "%s\t%s\n", credential.Name, credential.Source)
+		}
+		w.Flush()
+	},
+}
+
+var authRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a stored credential",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := credentials.Remove(profileCredential(args[0]))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Successfully removed '%s'!\n", args[0])
+	},
+}
+
+// profileCredential returns the name of the credential for the active
+// profile.
+func profileCredential(name string) string {
+	profile, err := config.ActiveProfile()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return credentials.ForProfile(name, profile)
+}
+
+func init() {
+	authCmd.AddCommand(authSetCmd)
+	authCmd.AddCommand(authListCmd)
+	authCmd.AddCommand(authRemoveCmd)
+	rootCmd.AddCommand(authCmd)
+}
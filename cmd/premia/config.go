@@ -0,0 +1,174 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var configSkipDatabase bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change premia's config",
+	Long: `Read and change premia's config. Keys are JSON paths separated by dots,
+e.g. "reportingCurrency", "instruments.stocks.compression.after" or
+"instruments.stocks.aggregates.0.refresh".`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := config.Get(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set the value of a key",
+	Long: `Set the value of a key. Values that are valid JSON, e.g. numbers or
+objects, are stored as such and everything else as string. The change is only
+written if the config stays valid.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.Set(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Successfully set '%s'!\n", args[0])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.Unset(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Successfully unset '%s'!\n", args[0])
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config against its schema and the database",
+	Long: `Check the config against its JSON Schema and check that all tables and
+views that it references exist in the database.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if configSkipDatabase {
+			fmt.Println("The config is valid.")
+			return
+		}
+
+		missingTables, err := storage.MissingTables(
+			storage.ConfiguredTables(configData),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(missingTables) > 0 {
+			for _, table := range missingTables {
+				fmt.Fprintf(os.Stderr, "'%s' doesn't exist in the database.\n", table)
+			}
+			os.Exit(1)
+		}
+
+		fmt.Println("The config is valid.")
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config in your editor",
+	Long: `Open a copy of the config in $EDITOR (default is vi). The config is only
+replaced if the edited copy is valid.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configFilePath, err := config.ConfigFilePath()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		content, err := os.ReadFile(configFilePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tmpDir, err := config.TmpDir(true)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tmpFile, err := os.CreateTemp(tmpDir, "config-*.json")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.Remove(tmpFile.Name())
+
+		_, err = tmpFile.Write(content)
+		tmpFile.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		editor := os.Getenv("EDITOR")
+		if editor == "" {
+			editor = "vi"
+		}
+
+		editorCmd := exec.Command(editor, tmpFile.Name())
+		editorCmd.Stdin = os.Stdin
+		editorCmd.Stdout = os.Stdout
+		editorCmd.Stderr = os.Stderr
+		err = editorCmd.Run()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		editedContent, err := os.ReadFile(tmpFile.Name())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = config.WriteConfigFile(editedContent)
+		if err != nil {
+			log.Fatalf("%v\nThe config was not changed.", err)
+		}
+
+		fmt.Println("Successfully updated config!")
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&configSkipDatabase, "skip-database", false, "Only check the config against its schema")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
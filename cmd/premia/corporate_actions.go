@@ -0,0 +1,65 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/dataprovider/polygon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	corporateActionsTickers []string
+	corporateActionsFrom    string
+	corporateActionsTo      string
+)
+
+var corporateActionsCmd = &cobra.Command{
+	Use:   "corporate-actions",
+	Short: "Import splits and dividends from polygon.io",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if configData.Instruments[config.Stocks].AdjustedTable == "" {
+			log.Fatal("The splits and dividends tables have not been set up. Please run 'premia init' first.")
+		}
+
+		apiParams := &dataprovider.ApiParams{
+			Tickers: corporateActionsTickers,
+			To:      time.Now(),
+		}
+		if corporateActionsFrom != "" {
+			apiParams.From, err = time.Parse(time.RFC3339, corporateActionsFrom)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if corporateActionsTo != "" {
+			apiParams.To, err = time.Parse(time.RFC3339, corporateActionsTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		err = polygon.ImportCorporateActions(apiParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully imported corporate actions!")
+	},
+}
+
+func init() {
+	corporateActionsCmd.Flags().StringSliceVar(&corporateActionsTickers, "tickers", nil, "Tickers to import splits and dividends for (separate values by ,)")
+	corporateActionsCmd.Flags().StringVar(&corporateActionsFrom, "from", "", "Start date of the corporate actions in RFC3339 format")
+	corporateActionsCmd.Flags().StringVar(&corporateActionsTo, "to", "", "End date of the corporate actions in RFC3339 format (default now)")
+	corporateActionsCmd.MarkFlagRequired("tickers")
+	rootCmd.AddCommand(corporateActionsCmd)
+}
@@ -0,0 +1,39 @@
+package premia
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/premia-ai/cli/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the database, config, migrations and API keys",
+	Long: `Check that the database is reachable and supports the configured backend,
+that the user can create tables, that the config, the migrations and the
+database match and that API keys for the data providers are stored. Every
+failed check is printed with a way to fix it.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		checks := doctor.Run()
+
+		failed := false
+		for _, check := range checks {
+			fmt.Printf("[%s] %s: %s\n", check.Status, check.Name, check.Message)
+			if check.Fix != "" {
+				fmt.Printf("       Fix: %s\n", check.Fix)
+			}
+			failed = failed || check.Status == doctor.Fail
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
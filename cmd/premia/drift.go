@@ -0,0 +1,78 @@
+package premia
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/premia-ai/cli/internal/drift"
+	"github.com/premia-ai/cli/internal/migrations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	driftJson   bool
+	driftAccept bool
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect differences between the config, the migrations and the database",
+	Long: `Detect migration files that were edited or deleted after they were applied
+or that differ from the templates rendered from the config, tables, views and
+functions that the applied migrations or the config define but that are
+missing in the database or that exist in the database without a migration,
+and tables, views and functions whose definitions were changed by hand.
+Checksums of migration files and definitions are recorded whenever migrations
+are applied. After an intended change, record the new checksums with --accept.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		if driftAccept {
+			err := drift.Accept()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println("Successfully recorded the checksums of the migrations!")
+			return
+		}
+
+		drifts, err := migrations.DetectDrift()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if driftJson {
+			if drifts == nil {
+				drifts = []drift.Drift{}
+			}
+			content, err := json.MarshalIndent(drifts, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println(string(content))
+		} else if len(drifts) == 0 {
+			fmt.Println("No drift found.")
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROBLEM\tKIND\tOBJECT\tDETAIL")
+			for _, d := range drifts {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Problem, d.Kind, d.Object, d.Detail)
+			}
+			w.Flush()
+		}
+
+		if len(drifts) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	driftCmd.Flags().BoolVar(&driftJson, "json", false, "Print the differences as JSON")
+	driftCmd.Flags().BoolVar(&driftAccept, "accept", false, "Record the checksums of the current migration files")
+	rootCmd.AddCommand(driftCmd)
+}
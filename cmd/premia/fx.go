@@ -0,0 +1,87 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/dataprovider/polygon"
+	"github.com/premia-ai/cli/internal/dataprovider/twelvedata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fxPairs    []string
+	fxProvider string
+	fxTimespan string
+	fxFrom     string
+	fxTo       string
+)
+
+var fxCmd = &cobra.Command{
+	Use:   "fx",
+	Short: "Import FX rates to convert prices into your reporting currency",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if configData.ReportingCurrency == "" {
+			log.Fatal("The FX rates table has not been set up. Please run 'premia init' first.")
+		}
+
+		timespan, err := dataprovider.GetTimespanInfo(fxTimespan)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fromTime, err := time.Parse(time.RFC3339, fxFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		toTime := time.Now()
+		if fxTo != "" {
+			toTime, err = time.Parse(time.RFC3339, fxTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		apiParams := &dataprovider.ApiParams{
+			Tickers:  fxPairs,
+			Timespan: timespan.Value,
+			Quantity: 1,
+			From:     fromTime,
+			To:       toTime,
+			Table:    dataprovider.FxRatesTable,
+		}
+
+		switch dataprovider.Provider(fxProvider) {
+		case dataprovider.Polygon:
+			err = polygon.ImportFxRates(apiParams)
+		case dataprovider.TwelveData:
+			err = twelvedata.ImportFxRates(apiParams)
+		default:
+			log.Fatalf("FX rates can't be imported from '%s'", fxProvider)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully imported FX rates!")
+	},
+}
+
+func init() {
+	fxCmd.Flags().StringSliceVar(&fxPairs, "pairs", nil, "Currency pairs to import, e.g. EUR/USD (separate values by ,)")
+	fxCmd.Flags().StringVar(&fxProvider, "provider", string(dataprovider.Polygon), "Data provider of the FX rates")
+	fxCmd.Flags().StringVar(&fxTimespan, "timespan", "day", "Timespan of the FX rates")
+	fxCmd.Flags().StringVar(&fxFrom, "from", "", "Start date of the FX rates in RFC3339 format")
+	fxCmd.Flags().StringVar(&fxTo, "to", "", "End date of the FX rates in RFC3339 format (default now)")
+	fxCmd.MarkFlagRequired("pairs")
+	fxCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(fxCmd)
+}
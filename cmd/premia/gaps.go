@@ -0,0 +1,128 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/premia-ai/cli/internal/calendar"
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/gaps"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gapsInstrument string
+	gapsExchange   string
+	gapsSymbols    []string
+	gapsFrom       string
+	gapsTo         string
+	gapsFill       bool
+)
+
+var gapsCmd = &cobra.Command{
+	Use:   "gaps",
+	Short: "Detect missing bars in your candle tables",
+	Long: `Detect missing bars per symbol based on the timespan of the base table
+and the trading calendar of the exchange. With --fill the missing bars are
+re-requested from the data provider that delivered the symbol's data.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		instrumentConfig, ok := configData.Instruments[config.InstrumentType(gapsInstrument)]
+		if !ok {
+			log.Fatalf("There is no '%s' table set up.", gapsInstrument)
+		}
+
+		timespan, err := dataprovider.GetTimespanInfo(instrumentConfig.TimespanUnit)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		exchange := gapsExchange
+		if exchange == "" {
+			exchange = instrumentConfig.Exchange
+		}
+		if exchange == "" {
+			exchange = calendar.DefaultExchange
+		}
+
+		exchangeCalendar, err := calendar.Get(exchange)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		params := gaps.Params{
+			Table:    instrumentConfig.BaseTable,
+			Timespan: timespan,
+			Quantity: instrumentConfig.BarQuantity(),
+			Calendar: exchangeCalendar,
+			Symbols:  gapsSymbols,
+		}
+		if gapsFrom != "" {
+			params.From, err = time.Parse(time.RFC3339, gapsFrom)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if gapsTo != "" {
+			params.To, err = time.Parse(time.RFC3339, gapsTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		foundGaps, err := gaps.Detect(&params)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(foundGaps) == 0 {
+			fmt.Printf("No gaps found in '%s'.\n", params.Table)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SYMBOL\tFROM\tTO\tBARS\tDATA PROVIDER")
+		for _, gap := range foundGaps {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%d\t%s\n",
+				gap.Symbol,
+				gap.From.Format(time.RFC3339),
+				gap.To.Format(time.RFC3339),
+				gap.Bars,
+				gap.DataProvider,
+			)
+		}
+		w.Flush()
+
+		if !gapsFill {
+			return
+		}
+
+		err = gaps.Fill(&params, foundGaps)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Successfully filled %d gaps!\n", len(foundGaps))
+	},
+}
+
+func init() {
+	gapsCmd.Flags().StringVar(&gapsInstrument, "instrument", string(config.Stocks), "Instrument type whose base table is checked")
+	gapsCmd.Flags().StringVar(&gapsExchange, "exchange", "", "Exchange whose trading calendar is used (default is the instrument's exchange)")
+	gapsCmd.Flags().StringSliceVar(&gapsSymbols, "symbols", nil, "Only check these symbols (separate values by ,)")
+	gapsCmd.Flags().StringVar(&gapsFrom, "from", "", "Start of the checked range in RFC3339 format")
+	gapsCmd.Flags().StringVar(&gapsTo, "to", "", "End of the checked range in RFC3339 format")
+	gapsCmd.Flags().BoolVar(&gapsFill, "fill", false, "Re-request the missing bars and upsert them")
+	rootCmd.AddCommand(gapsCmd)
+}
@@ -9,18 +9,55 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	initLocal   bool
+	initBackend string
+)
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a financial database",
-	Args:  cobra.ExactArgs(0),
+	Long: `Initialize a financial database. The config and migrations are stored in
+the .premia directory that is closest to the working directory or in the home
+directory. With --local they are stored in a new .premia directory in the
+working directory, so that they can be committed next to your code.
+
+With --backend postgres no TimescaleDB extension is needed: base tables are
+partitioned by year with native range partitioning and aggregates are regular
+materialized views that are filled by 'premia aggregate refresh'. Postgres 14
+or newer is required.
+
+With --backend duckdb no server is needed: the same schema is stored in a local
+DuckDB file, by default premia.duckdb in the .premia directory or the file set
+as database.path. Compression and retention are not available.
+
+Re-running init keeps the backend of the profile, the backend of a profile with
+instruments cannot be changed.`,
+	Args: cobra.ExactArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
+		if initLocal {
+			err := config.UseLocalDir()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
 		_, err := config.SetupConfigDir()
 		if err != nil {
 			log.Fatal("SetupConfigDir:", err)
 		}
+		// Without --backend the backend of an existing profile is kept
+		if cmd.Flags().Changed("backend") {
+			err = config.SetBackend(initBackend)
+		} else {
+			err = config.SetDefaultBackend(initBackend)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
 		err = migrations.Initialize()
 		if err != nil {
-			log.Fatal("Initialize:", err)
+			log.Fatalf("Initialize: %v\nRun 'premia doctor' to check your setup.", err)
 		}
 		err = migrations.Seed()
 		if err != nil {
@@ -32,5 +69,7 @@ var initCmd = &cobra.Command{
 }
 
 func init() {
+	initCmd.Flags().BoolVar(&initLocal, "local", false, "Create a project-local .premia directory in the working directory")
+	initCmd.Flags().StringVar(&initBackend, "backend", config.BackendTimescale, "Database backend, one of timescale, postgres or duckdb")
 	rootCmd.AddCommand(initCmd)
 }
@@ -0,0 +1,85 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var profilePostgresUrl string
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage profiles for separate databases and environments",
+	Long: `Manage profiles for separate databases and environments. Every profile
+has its own config, migrations and database connection. The default profile is
+stored in the .premia directory itself and all other profiles in its profiles
+directory.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all profiles, the active one is marked with *",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := config.Profiles()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		activeProfile, err := config.ActiveProfile()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, p := range profiles {
+			marker := " "
+			if p == activeProfile {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, p)
+		}
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.CreateProfile(args[0], profilePostgresUrl)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf(
+			"Successfully created profile '%s'! Run 'premia --profile %s init' to set up its database.\n",
+			args[0],
+			args[0],
+		)
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a profile the active one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.UseProfile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Now using profile '%s'.\n", args[0])
+	},
+}
+
+func init() {
+	profileCreateCmd.Flags().StringVar(&profilePostgresUrl, "postgres-url", "", "Database of the profile (default is $POSTGRES_URL)")
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	rootCmd.AddCommand(profileCmd)
+}
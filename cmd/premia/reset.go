@@ -0,0 +1,48 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/premia-ai/cli/internal/migrations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetKeepData  bool
+	resetExportDir string
+	resetYes       bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Drop the database objects of premia and delete the config",
+	Long: `Undo 'premia init': run the down migrations of all applied migrations in
+reverse order and delete the config, the migrations and the temporary files of
+the active profile. The migrations that will be run and every table, view and
+function that they drop are listed for confirmation first. Tables that are
+dropped aren't decompressed before, and extensions that other objects still
+use are kept. With --keep-data all tables are exported as CSV files before
+anything is dropped.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		options := migrations.ResetOptions{Yes: resetYes}
+		if resetKeepData {
+			options.ExportDir = resetExportDir
+		}
+
+		err := migrations.Reset(options)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully reset premia! Run 'premia init' to start over.")
+	},
+}
+
+func init() {
+	resetCmd.Flags().BoolVar(&resetKeepData, "keep-data", false, "Export all tables as CSV files before they are dropped")
+	resetCmd.Flags().StringVar(&resetExportDir, "export-dir", "premia-export", "Directory of the CSV files that --keep-data exports")
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Skip the confirmation")
+	rootCmd.AddCommand(resetCmd)
+}
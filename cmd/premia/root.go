@@ -4,18 +4,32 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
 	"github.com/spf13/cobra"
 )
 
+var profile string
+
 var rootCmd = &cobra.Command{
 	Use:   "premia",
 	Short: "premia - a CLI to setup financial infrastructure",
 	Long:  `premia is a CLI to setup common infrastructure for asset management firms`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		config.SetProfile(profile)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		database.Close()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile whose config, migrations and database are used (default is $PREMIA_PROFILE or the profile selected with 'premia profile use')")
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "There was an error while executing '%s'", err)
@@ -0,0 +1,103 @@
+package premia
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/status"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusSymbol string
+	statusJson   bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the tables of your instruments and the data they contain",
+	Long: `List the base table, aggregates and features of every configured
+instrument with whether they exist in the database, their number of rows and
+symbols and the time range they cover. With --symbol only the rows of that
+symbol are counted. With --json the inventory is printed as JSON, e.g. for
+dashboards.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		report, err := status.Collect(configData, statusSymbol)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if statusJson {
+			content, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println(string(content))
+			return
+		}
+
+		if len(report.Instruments) == 0 {
+			fmt.Println("There are no instruments set up.")
+			return
+		}
+
+		for i, instrument := range report.Instruments {
+			if i > 0 {
+				fmt.Println()
+			}
+			if statusSymbol != "" {
+				fmt.Printf("%s (%s)\n", instrument.Type, statusSymbol)
+			} else {
+				fmt.Println(instrument.Type)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if statusSymbol != "" {
+				fmt.Fprintln(w, "TABLE\tKIND\tEXISTS\tROWS\tFROM\tTO")
+			} else {
+				fmt.Fprintln(w, "TABLE\tKIND\tEXISTS\tROWS\tSYMBOLS\tFROM\tTO")
+			}
+			for _, table := range instrument.Tables {
+				exists := "no"
+				if table.NotPopulated {
+					exists = "not populated"
+				} else if table.Exists {
+					exists = "yes"
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t", table.Name, table.Kind, exists, table.Rows)
+				if statusSymbol == "" {
+					fmt.Fprintf(w, "%d\t", table.Symbols)
+				}
+				fmt.Fprintf(w, "%s\t%s\n", formatTime(table.From), formatTime(table.To))
+			}
+			w.Flush()
+		}
+	},
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusSymbol, "symbol", "", "Only count the rows of this symbol")
+	statusCmd.Flags().BoolVar(&statusJson, "json", false, "Print the inventory as JSON")
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,135 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/migrations"
+	"github.com/premia-ai/cli/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storageInstrument    string
+	storageCompressAfter string
+	storageTable         string
+	storageDropAfter     string
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage how your market data is stored",
+}
+
+var storageCompressCmd = &cobra.Command{
+	Use:   "compress",
+	Short: "Enable native compression for a base table",
+	Long: `Enable native compression segmented by symbol for a base table, add a
+compression policy and compress all chunks that are older than the policy's
+interval right away.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		configData, err := config.Config()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = configData.RequireTimescale("Compression")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		instrumentConfig, ok := configData.Instruments[config.InstrumentType(storageInstrument)]
+		if !ok {
+			log.Fatalf("There is no '%s' table set up.", storageInstrument)
+		}
+		table := instrumentConfig.BaseTable
+
+		sizeBefore, err := storage.TableSize(table)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = migrations.EnableCompression(
+			config.InstrumentType(storageInstrument),
+			storageCompressAfter,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		compressedChunks, err := storage.CompressChunks(table, storageCompressAfter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sizeAfter, err := storage.TableSize(table)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		chunkSizes, err := storage.CompressedChunkSizes(table)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHUNK\tBEFORE\tAFTER")
+		for _, chunkSize := range chunkSizes {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\n",
+				chunkSize.Chunk,
+				storage.FormatBytes(chunkSize.BeforeBytes),
+				storage.FormatBytes(chunkSize.AfterBytes),
+			)
+		}
+		w.Flush()
+
+		fmt.Printf(
+			"\nCompressed %d chunks of '%s': %s before, %s after.\n",
+			compressedChunks,
+			table,
+			storage.FormatBytes(sizeBefore),
+			storage.FormatBytes(sizeAfter),
+		)
+	},
+}
+
+var storageRetentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Drop data of a base table or aggregate after an interval",
+	Long: `Add a retention policy to a base table or one of its aggregates. Data of
+the base table, or of an aggregate that other aggregates are built on, is only
+dropped if a continuous aggregate covering the dropped period exists and has
+been refreshed. The interval has to be longer than the refresh window of every
+aggregate built on the table, otherwise refreshes would erase their buckets.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := migrations.SetRetention(
+			config.InstrumentType(storageInstrument),
+			storageTable,
+			storageDropAfter,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully set retention policy!")
+	},
+}
+
+func init() {
+	storageCompressCmd.Flags().StringVar(&storageInstrument, "instrument", string(config.Stocks), "Instrument type whose base table is compressed")
+	storageCompressCmd.Flags().StringVar(&storageCompressAfter, "after", "7 days", "Interval after which data is compressed")
+	storageRetentionCmd.Flags().StringVar(&storageInstrument, "instrument", string(config.Stocks), "Instrument type whose tables are affected")
+	storageRetentionCmd.Flags().StringVar(&storageTable, "table", "", "Base table or aggregate to drop data from (default is the base table)")
+	storageRetentionCmd.Flags().StringVar(&storageDropAfter, "drop-after", "", "Interval after which data is dropped, e.g. '90 days'")
+	storageRetentionCmd.MarkFlagRequired("drop-after")
+	storageCmd.AddCommand(storageCompressCmd)
+	storageCmd.AddCommand(storageRetentionCmd)
+	rootCmd.AddCommand(storageCmd)
+}
@@ -0,0 +1,49 @@
+package premia
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/migrations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeInstrument string
+	upgradeVolumeType string
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the schema of an existing financial database",
+}
+
+var upgradeVolumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Widen the volume column of a base table",
+	Long: `Widen the volume column of a base table to avoid overflows of high-volume
+instruments. Views that are based on the table are recreated from their
+definitions in the database and continuous aggregates keep their policies and
+are refreshed afterwards. Tables with compression enabled and aggregates whose
+raw data was dropped by a retention policy cannot be widened.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := migrations.WidenVolume(
+			config.InstrumentType(upgradeInstrument),
+			upgradeVolumeType,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Successfully upgraded volume column!")
+	},
+}
+
+func init() {
+	upgradeVolumeCmd.Flags().StringVar(&upgradeInstrument, "instrument", string(config.Stocks), "Instrument type whose base table is upgraded")
+	upgradeVolumeCmd.Flags().StringVar(&upgradeVolumeType, "type", config.VolumeNumeric, "New type of the volume column (NUMERIC or BIGINT)")
+	upgradeCmd.AddCommand(upgradeVolumeCmd)
+	rootCmd.AddCommand(upgradeCmd)
+}
@@ -0,0 +1,111 @@
+package aggregates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/premia-ai/cli/internal/database"
+)
+
+// Refresh materializes the buckets of the aggregates between from and to,
+// e.g. after older data has been backfilled which the refresh policies
+// don't cover.
+func Refresh(tables []string, from, to time.Time) error {
+	pool, err := database.Pool()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		// refresh_continuous_aggregate can't be run inside a transaction,
+		// so the aggregates are refreshed one after another
+		_, err = pool.Exec(
+			context.Background(),
+			"CALL refresh_continuous_aggregate($1::regclass, $2::timestamptz, $3::timestamptz);",
+			table,
+			from,
+			to,
+		)
+		if err != nil {
+			return errors.New(
+				fmt.Sprintf("Refreshing '%s' failed: %v", table, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// RefreshAll materializes all buckets of the aggregates, e.g. after they
+// were recreated. Aggregates need to be passed after the aggregates they are
+// based on.
+func RefreshAll(tables []string) error {
+	pool, err := database.Pool()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		_, err = pool.Exec(
+			context.Background(),
+			"CALL refresh_continuous_aggregate($1::regclass, NULL, NULL);",
+			table,
+		)
+		if err != nil {
+			return errors.New(
+				fmt.Sprintf("Refreshing '%s' failed: %v", table, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// RefreshViews recomputes the materialized views of the postgres backend
+// completely. Aggregates need to be passed after the aggregates they are
+// based on.
+func RefreshViews(tables []string) error {
+	pool, err := database.Pool()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		// Views can only be refreshed concurrently once they have been
+		// filled, concurrent refreshes don't block queries of the view
+		var populated bool
+		err = pool.QueryRow(
+			context.Background(),
+			"SELECT ispopulated FROM pg_matviews WHERE matviewname = $1;",
+			table,
+		).Scan(&populated)
+		if err == pgx.ErrNoRows {
+			return errors.New(
+				fmt.Sprintf("'%s' is no materialized view", table),
+			)
+		}
+		if err != nil {
+			return err
+		}
+
+		refresh := "REFRESH MATERIALIZED VIEW %s;"
+		if populated {
+			refresh = "REFRESH MATERIALIZED VIEW CONCURRENTLY %s;"
+		}
+		_, err = pool.Exec(
+			context.Background(),
+			fmt.Sprintf(refresh, pgx.Identifier{table}.Sanitize()),
+		)
+		if err != nil {
+			return errors.New(
+				fmt.Sprintf("Refreshing '%s' failed: %v", table, err),
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/premia-ai/cli/resource"
+)
+
+const DefaultExchange = "XNYS"
+
+const dateFormat = time.DateOnly
+
+const clockFormat = "15:04"
+
+// Calendar describes the regular trading hours of an exchange. The bundled
+// holidays and early closes cover the dates between From and To, outside of
+// that range every weekday is treated as a trading day.
+type Calendar struct {
+	Exchange    string   `json:"exchange"`
+	Name        string   `json:"name"`
+	Timezone    string   `json:"timezone"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Open        string   `json:"open"`
+	Close       string   `json:"close"`
+	EarlyClose  string   `json:"earlyClose,omitempty"`
+	Holidays    []string `json:"holidays"`
+	EarlyCloses []string `json:"earlyCloses"`
+
+	location        *time.Location
+	openClock       time.Time
+	closeClock      time.Time
+	earlyCloseClock time.Time
+	holidays        map[string]bool
+	earlyCloses     map[string]bool
+}
+
+type Session struct {
+	Date       time.Time
+	Open       time.Time
+	Close      time.Time
+	EarlyClose bool
+}
+
+func Exchanges() ([]string, error) {
+	entries, err := resource.Fs.ReadDir(resource.CalendarsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchanges []string
+	for _, entry := range entries {
+		exchanges = append(exchanges, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(exchanges)
+
+	return exchanges, nil
+}
+
+func All() ([]*Calendar, error) {
+	exchanges, err := Exchanges()
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []*Calendar
+	for _, exchange := range exchanges {
+		calendar, err := Get(exchange)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, calendar)
+	}
+
+	return calendars, nil
+}
+
+func Get(exchange string) (*Calendar, error) {
+	content, err := resource.Fs.ReadFile(
+		path.Join(resource.CalendarsPath, exchange+".json"),
+	)
+	if err != nil {
+		return nil, errors.New(
+			fmt.Sprintf("There is no trading calendar for exchange '%s'", exchange),
+		)
+	}
+
+	return Parse(content)
+}
+
+// Parse reads a calendar in the format of the bundled calendars.
+func Parse(content []byte) (*Calendar, error) {
+	var calendar Calendar
+	err := json.Unmarshal(content, &calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.location, err = time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.openClock, err = time.Parse(clockFormat, calendar.Open)
+	if err != nil {
+		return nil, err
+	}
+	calendar.closeClock, err = time.Parse(clockFormat, calendar.Close)
+	if err != nil {
+		return nil, err
+	}
+	calendar.earlyCloseClock = calendar.closeClock
+	if calendar.EarlyClose != "" {
+		calendar.earlyCloseClock, err = time.Parse(clockFormat, calendar.EarlyClose)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	calendar.holidays = make(map[string]bool)
+	for _, date := range calendar.Holidays {
+		calendar.holidays[date] = true
+	}
+	calendar.earlyCloses = make(map[string]bool)
+	for _, date := range calendar.EarlyCloses {
+		calendar.earlyCloses[date] = true
+	}
+
+	return &calendar, nil
+}
+
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+func (c *Calendar) IsTradingDay(date time.Time) bool {
+	weekday := date.Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+
+	return !c.holidays[date.Format(dateFormat)]
+}
+
+// Sessions returns the trading sessions of all days between from and to
+// (both inclusive) in the exchange's local time.
+func (c *Calendar) Sessions(from, to time.Time) []Session {
+	from = from.In(c.location)
+	to = to.In(c.location)
+
+	var sessions []Session
+	date := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.location)
+	for !date.After(to) {
+		if c.IsTradingDay(date) {
+			session := Session{
+				Date:       date,
+				Open:       clockTime(date, c.openClock),
+				Close:      clockTime(date, c.closeClock),
+				EarlyClose: c.earlyCloses[date.Format(dateFormat)],
+			}
+			if session.EarlyClose {
+				session.Close = clockTime(date, c.earlyCloseClock)
+			}
+			sessions = append(sessions, session)
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+
+	return sessions
+}
+
+// clockTime avoids adding durations to midnight, which would be off by an hour
+// on days with daylight saving time transitions.
+func clockTime(date time.Time, clock time.Time) time.Time {
+	return time.Date(
+		date.Year(),
+		date.Month(),
+		date.Day(),
+		clock.Hour(),
+		clock.Minute(),
+		0,
+		0,
+		date.Location(),
+	)
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path"
+	"strings"
+
+	"github.com/premia-ai/cli/internal/dataprovider"
 )
 
 type InstrumentType string
@@ -16,23 +18,24 @@ const (
 	Options InstrumentType = "options"
 )
 
+// getDir returns the path of a directory inside the .premia directory.
 func getDir(dirPath string, createIfMissing bool) (string, error) {
-	homeDir, err := os.UserHomeDir()
+	premiaDir, err := PremiaDir()
 	if err != nil {
 		return "", err
 	}
 
-	dir := path.Join(homeDir, dirPath)
+	dir := path.Join(premiaDir, dirPath)
 
 	_, err = os.Stat(dir)
 	if os.IsNotExist(err) {
-		err = os.Mkdir(dir, 0777)
 		if !createIfMissing {
 			return "", errors.New(
-				fmt.Sprintf("'%s' directory doesn't exist.", dirPath),
+				fmt.Sprintf("'%s' directory doesn't exist.", dir),
 			)
 		}
 
+		err = os.MkdirAll(dir, 0777)
 		if err != nil {
 			return "", err
 		}
@@ -41,31 +44,208 @@ func getDir(dirPath string, createIfMissing bool) (string, error) {
 	return dir, err
 }
 
+// ConfigDir returns the directory of the active profile.
 func ConfigDir(createIfMissing bool) (string, error) {
-	return getDir(".premia", createIfMissing)
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+
+	return getDir(profileDirPath(profile), createIfMissing)
 }
 
 func MigrationsDir(createIfMissing bool) (string, error) {
-	return getDir(".premia/migrations", createIfMissing)
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+
+	return getDir(
+		path.Join(profileDirPath(profile), "migrations"),
+		createIfMissing,
+	)
 }
 
 func TmpDir(createIfMissing bool) (string, error) {
-	return getDir(".premia/tmp", createIfMissing)
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+
+	return getDir(path.Join(profileDirPath(profile), "tmp"), createIfMissing)
 }
 
 type ConfigFileData struct {
-	Version     string                              `json:"version"`
-	Instruments map[InstrumentType]InstrumentConfig `json:"instruments,omitempty"`
+	Version string `json:"version"`
+	// PostgresUrl is the database of the profile, POSTGRES_URL is used if
+	// it's empty
+	PostgresUrl       string                              `json:"postgresUrl,omitempty"`
+	ReportingCurrency string                              `json:"reportingCurrency,omitempty"`
+	Instruments       map[InstrumentType]InstrumentConfig `json:"instruments,omitempty"`
+	// Database is nil when the connection is configured by the URL only
+	Database *DatabaseConfig `json:"database,omitempty"`
+	// Backend is empty for databases that were set up with TimescaleDB
+	// before plain Postgres was supported
+	Backend string `json:"backend,omitempty"`
 }
 
+const (
+	BackendTimescale = "timescale"
+	// BackendPostgres uses native range partitioning and regular
+	// materialized views instead of TimescaleDB
+	BackendPostgres = "postgres"
+	// BackendDuckDB stores everything in a local DuckDB file, aggregates are
+	// regular views
+	BackendDuckDB = "duckdb"
+)
+
+var Backends = []string{BackendTimescale, BackendPostgres, BackendDuckDB}
+
+// DatabaseBackend returns the backend that the database was set up with.
+func (c *ConfigFileData) DatabaseBackend() string {
+	if c.Backend == "" {
+		return BackendTimescale
+	}
+
+	return c.Backend
+}
+
+// RequireTimescale returns an error if the feature is not available because
+// the database doesn't use TimescaleDB.
+func (c *ConfigFileData) RequireTimescale(feature string) error {
+	if c.DatabaseBackend() == BackendTimescale {
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf(
+		"%s requires TimescaleDB, but the database uses the %s backend",
+		feature,
+		c.DatabaseBackend(),
+	))
+}
+
+const (
+	VolumeNumeric = "NUMERIC"
+	VolumeBigint  = "BIGINT"
+	// Tables created by earlier versions of premia store volumes as INT
+	VolumeInt = "INT"
+)
+
+var VolumeTypes = []string{VolumeNumeric, VolumeBigint}
+
 type InstrumentConfig struct {
 	BaseTable    string `json:"baseTable,omitempty"`
 	TimespanUnit string `json:"timespan,omitempty"`
+	// Quantity is the multiplier of the timespan, e.g. 5 for 5 minute bars
+	Quantity      int    `json:"quantity,omitempty"`
+	Exchange      string `json:"exchange,omitempty"`
+	AdjustedTable string `json:"adjustedTable,omitempty"`
+	// Views of the base and adjusted table in the reporting currency
+	ConvertedTables []string          `json:"convertedTables,omitempty"`
+	VolumeType      string            `json:"volumeType,omitempty"`
+	Aggregates      []AggregateConfig `json:"aggregates,omitempty"`
+	Features        []FeatureConfig   `json:"features,omitempty"`
+	// Compression is nil when compression isn't enabled for the base table
+	Compression *CompressionConfig `json:"compression,omitempty"`
+	// Retention is nil when the base table's data is kept forever
+	Retention *RetentionConfig `json:"retention,omitempty"`
+}
+
+// BarQuantity returns the multiplier of the base table's timespan. Configs of
+// earlier versions of premia only contain single unit bars.
+func (i InstrumentConfig) BarQuantity() int {
+	if i.Quantity == 0 {
+		return 1
+	}
+
+	return i.Quantity
+}
+
+type RetentionConfig struct {
+	// Interval after which data gets dropped, e.g. "90 days"
+	DropAfter string `json:"dropAfter"`
+}
+
+type CompressionConfig struct {
+	// Interval after which chunks get compressed, e.g. "7 days"
+	After string `json:"after"`
+}
+
+type AggregateConfig struct {
+	Table          string           `json:"table"`
+	TimespanUnit   string           `json:"timespan"`
+	Quantity       int              `json:"quantity"`
+	ReferenceTable string           `json:"referenceTable"`
+	Retention      *RetentionConfig `json:"retention,omitempty"`
+	// Refresh is nil when the defaults for the aggregate's timespan are used
+	Refresh *RefreshConfig `json:"refresh,omitempty"`
+}
+
+type RefreshConfig struct {
+	StartOffset      string `json:"startOffset"`
+	EndOffset        string `json:"endOffset"`
+	ScheduleInterval string `json:"scheduleInterval"`
+}
+
+// DefaultRefreshConfig refreshes the last three buckets except the current
+// one once per bucket.
+func DefaultRefreshConfig(quantity int, timespanUnit string) *RefreshConfig {
+	return &RefreshConfig{
+		StartOffset:      interval(3*quantity, timespanUnit),
+		EndOffset:        interval(quantity, timespanUnit),
+		ScheduleInterval: interval(quantity, timespanUnit),
+	}
+}
+
+// Interval returns the bucket width of the aggregate as a Postgres interval.
+func (a *AggregateConfig) Interval() string {
+	return interval(a.Quantity, a.TimespanUnit)
+}
+
+func interval(quantity int, timespanUnit string) string {
+	timespan, err := dataprovider.GetTimespanInfo(timespanUnit)
+	if err != nil {
+		return fmt.Sprintf("%d %s", quantity, timespanUnit)
+	}
+
+	return timespan.Interval(quantity)
+}
+
+func (a *AggregateConfig) RefreshConfig() *RefreshConfig {
+	if a.Refresh != nil {
+		return a.Refresh
+	}
+
+	return DefaultRefreshConfig(a.Quantity, a.TimespanUnit)
+}
+
+type FeatureConfig struct {
+	// Name of the feature's template
+	Name           string `json:"name"`
+	TimespanUnit   string `json:"timespan"`
+	Quantity       int    `json:"quantity"`
+	ReferenceTable string `json:"referenceTable"`
+	// Window is the number of bars that are used by window functions like
+	// moving averages
+	Window int `json:"window,omitempty"`
+	// ViewName is only set for views that were created before their name
+	// contained the window, see View
+	ViewName string `json:"view,omitempty"`
+}
+
+// WindowSize returns the feature's window. Features of earlier versions of
+// premia used their quantity as window.
+func (f *FeatureConfig) WindowSize() int {
+	if f.Window == 0 {
+		return f.Quantity
+	}
+
+	return f.Window
 }
 
 func CreateConfigFileData(baseTable, timespanUnit string) *ConfigFileData {
 	return &ConfigFileData{
-		Version: "1",
+		Version: CurrentVersion,
 	}
 }
 
@@ -73,68 +253,140 @@ func UpdateConfig(
 	instrument InstrumentType,
 	data *InstrumentConfig,
 ) error {
-	configFile, err := configFile()
-	if err != nil {
-		return err
+	return updateConfigData(func(configData *ConfigFileData) {
+		// Set potentially empty map
+		if configData.Instruments == nil {
+			configData.Instruments = make(map[InstrumentType]InstrumentConfig)
+		}
+		configData.Instruments[instrument] = *data
+	})
+}
+
+func SetBackend(backend string) error {
+	validBackend := false
+	for _, b := range Backends {
+		validBackend = validBackend || b == backend
+	}
+	if !validBackend {
+		return errors.New(fmt.Sprintf(
+			"Backend '%s' is not supported, please use one of: %s",
+			backend,
+			strings.Join(Backends, ", "),
+		))
 	}
-	defer configFile.Close()
 
-	jsonData, err := io.ReadAll(configFile)
+	configData, err := existingConfig()
 	if err != nil {
 		return err
 	}
+	if configData != nil &&
+		len(configData.Instruments) > 0 &&
+		configData.DatabaseBackend() != backend {
+		return errors.New(fmt.Sprintf(
+			"The profile already uses the %s backend for its instruments and cannot be switched to %s, please create a new profile with 'premia profile create' instead",
+			configData.DatabaseBackend(),
+			backend,
+		))
+	}
 
-	var configData ConfigFileData
-	err = json.Unmarshal(jsonData, &configData)
+	return updateConfigData(func(configData *ConfigFileData) {
+		configData.Backend = backend
+	})
+}
+
+// SetDefaultBackend sets the backend only if the config has none yet. Configs
+// with instruments but without a backend were written for TimescaleDB.
+func SetDefaultBackend(backend string) error {
+	configData, err := existingConfig()
 	if err != nil {
 		return err
 	}
-
-	// Set potentially empty map
-	if configData.Instruments == nil {
-		configData.Instruments = make(map[InstrumentType]InstrumentConfig)
+	if configData != nil && (configData.Backend != "" || len(configData.Instruments) > 0) {
+		return nil
 	}
-	configData.Instruments[instrument] = *data
 
-	err = configFile.Truncate(0)
+	return SetBackend(backend)
+}
+
+func SetReportingCurrency(currency string) error {
+	return updateConfigData(func(configData *ConfigFileData) {
+		configData.ReportingCurrency = currency
+	})
+}
+
+func updateConfigData(update func(configData *ConfigFileData)) error {
+	// Create the config file if it doesn't exist yet
+	configFile, err := configFile()
 	if err != nil {
 		return err
 	}
-	_, err = configFile.Seek(0, 0)
+	configFile.Close()
+
+	jsonData, err := readConfigContent()
 	if err != nil {
 		return err
 	}
 
-	fileContent, err := jsonPrettyPrint(configData)
+	var configData ConfigFileData
+	err = json.Unmarshal(jsonData, &configData)
 	if err != nil {
 		return err
 	}
 
-	_, err = configFile.Write(fileContent)
+	update(&configData)
+
+	fileContent, err := jsonPrettyPrint(configData)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return WriteConfigFile(fileContent)
 }
 
 func Config() (*ConfigFileData, error) {
-	configDir, err := ConfigDir(false)
+	configFilePath, err := ConfigFilePath()
 	if err != nil {
 		return nil, err
 	}
-	content, err := os.ReadFile(path.Join(configDir, "config.json"))
+	content, err := readConfigContent()
 	if err != nil {
 		return nil, err
 	}
 
-	var data *ConfigFileData
+	err = Validate(content)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"%v\nPlease fix '%s', e.g. with 'premia config edit'",
+			err,
+			configFilePath,
+		))
+	}
+
+	var data ConfigFileData
 	err = json.Unmarshal(content, &data)
 	if err != nil {
 		return nil, err
 	}
+	if data.Instruments == nil {
+		data.Instruments = make(map[InstrumentType]InstrumentConfig)
+	}
 
-	return data, nil
+	return &data, nil
+}
+
+// Instrument returns the config of the instrument type or an error if its
+// tables haven't been set up.
+func (c *ConfigFileData) Instrument(
+	instrumentType InstrumentType,
+) (InstrumentConfig, error) {
+	instrumentConfig, ok := c.Instruments[instrumentType]
+	if !ok {
+		return InstrumentConfig{}, errors.New(
+			fmt.Sprintf("There is no '%s' table set up.", instrumentType),
+		)
+	}
+
+	return instrumentConfig, nil
 }
 
 func configFile() (*os.File, error) {
@@ -178,6 +430,11 @@ func SetupConfigDir() (string, error) {
 		return "", err
 	}
 
+	err = writeLocalGitignore()
+	if err != nil {
+		return "", err
+	}
+
 	f, err := configFile()
 	if err != nil {
 		return "", err
@@ -197,6 +454,88 @@ func SetupConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// SetupFiles returns the files and directories of the active profile that
+// SetupConfigDir and the duckdb backend create. Files that don't exist are
+// skipped and a DuckDB file outside of the profile's directory is kept.
+func SetupFiles() ([]string, error) {
+	configDir, err := ConfigDir(false)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{
+		path.Join(configDir, "config.json"),
+		path.Join(configDir, "migrations"),
+		path.Join(configDir, "tmp"),
+	}
+
+	backend, err := Backend()
+	if err != nil {
+		return nil, err
+	}
+	if backend == BackendDuckDB {
+		duckDBPath, err := DuckDBPath()
+		if err != nil {
+			return nil, err
+		}
+		if path.Dir(duckDBPath) == configDir {
+			candidates = append(candidates, duckDBPath, duckDBPath+".wal")
+		}
+	}
+
+	var files []string
+	for _, candidate := range candidates {
+		_, err = os.Stat(candidate)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, candidate)
+	}
+
+	return files, nil
+}
+
+// RemoveSetupFiles deletes the SetupFiles of the active profile. The
+// directory of a profile other than the default one is deleted as well if
+// nothing else is left in it.
+func RemoveSetupFiles() error {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return err
+	}
+
+	configDir, err := ConfigDir(false)
+	if err != nil {
+		return err
+	}
+
+	files, err := SetupFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err = os.RemoveAll(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	if profile == DefaultProfile {
+		return nil
+	}
+
+	entries, err := os.ReadDir(configDir)
+	if err != nil || len(entries) > 0 {
+		return err
+	}
+
+	return os.Remove(configDir)
+}
+
 func jsonPrettyPrint(value any) ([]byte, error) {
 	result, err := json.MarshalIndent(value, "", "  ")
 	if err != nil {
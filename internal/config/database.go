@@ -0,0 +1,72 @@
+package config
+
+import "path"
+
+// DatabaseConfig contains the connection settings that override the parts of
+// the postgresUrl and the ones that can't be expressed in it. Settings are
+// named by their key in the config file in connection errors, e.g.
+// "database.sslRootCert".
+type DatabaseConfig struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	Name string `json:"name,omitempty"`
+	User string `json:"user,omitempty"`
+	// SslMode is one of the sslmode values of libpq, e.g. "verify-full"
+	SslMode     string `json:"sslMode,omitempty"`
+	SslRootCert string `json:"sslRootCert,omitempty"`
+	SslCert     string `json:"sslCert,omitempty"`
+	SslKey      string `json:"sslKey,omitempty"`
+	// StatementTimeout is a duration like "30s" or "5m", statements run
+	// without a timeout if it's empty
+	StatementTimeout string `json:"statementTimeout,omitempty"`
+	ApplicationName  string `json:"applicationName,omitempty"`
+	MaxConnections   int    `json:"maxConnections,omitempty"`
+	// Path is the file of the duckdb backend, it defaults to premia.duckdb
+	// in the profile's directory
+	Path string `json:"path,omitempty"`
+}
+
+// Database returns the connection settings of the active profile.
+func Database() (*DatabaseConfig, error) {
+	configData, err := existingConfig()
+	if err != nil {
+		return nil, err
+	}
+	if configData == nil || configData.Database == nil {
+		return &DatabaseConfig{}, nil
+	}
+
+	return configData.Database, nil
+}
+
+// Backend returns the database backend of the active profile. Profiles
+// without a config yet use TimescaleDB.
+func Backend() (string, error) {
+	configData, err := existingConfig()
+	if err != nil {
+		return "", err
+	}
+	if configData == nil {
+		return BackendTimescale, nil
+	}
+
+	return configData.DatabaseBackend(), nil
+}
+
+// DuckDBPath returns the file of the duckdb backend.
+func DuckDBPath() (string, error) {
+	databaseConfig, err := Database()
+	if err != nil {
+		return "", err
+	}
+	if databaseConfig.Path != "" {
+		return databaseConfig.Path, nil
+	}
+
+	configDir, err := ConfigDir(false)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(configDir, "premia.duckdb"), nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const premiaDirName = ".premia"
+
+// localDir is set when a project-local .premia directory is created in the
+// working directory, e.g. by 'premia init --local'.
+var localDir string
+
+// UseLocalDir makes the CLI use a .premia directory in the working directory
+// even if it doesn't exist yet.
+func UseLocalDir() error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	localDir = filepath.Join(workingDir, premiaDirName)
+	return nil
+}
+
+// PremiaDir returns the .premia directory that is closest to the working
+// directory and falls back to the one in the home directory. Project-local
+// directories allow teams to commit their config and migrations.
+func PremiaDir() (string, error) {
+	if localDir != "" {
+		return localDir, nil
+	}
+
+	dir, err := os.Getwd()
+	if err == nil {
+		for {
+			candidate := filepath.Join(dir, premiaDirName)
+			info, err := os.Stat(candidate)
+			if err == nil && info.IsDir() {
+				return candidate, nil
+			}
+
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, premiaDirName), nil
+}
+
+// IsLocalDir reports whether the .premia directory in use is a project-local
+// one instead of the one in the home directory.
+func IsLocalDir() (bool, error) {
+	dir, err := PremiaDir()
+	if err != nil {
+		return false, err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+
+	return dir != path.Join(homeDir, premiaDirName), nil
+}
+
+// localGitignore keeps files that are specific to a machine out of version
+// control.
+const localGitignore = `tmp/
+profiles/*/tmp/
+profile
+*.duckdb
+*.duckdb.wal
+`
+
+// writeLocalGitignore adds a .gitignore to project-local .premia directories
+// that don't have one yet.
+func writeLocalGitignore() error {
+	isLocal, err := IsLocalDir()
+	if err != nil || !isLocal {
+		return err
+	}
+
+	dir, err := PremiaDir()
+	if err != nil {
+		return err
+	}
+
+	gitignorePath := path.Join(dir, ".gitignore")
+	_, err = os.Stat(gitignorePath)
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.WriteFile(gitignorePath, []byte(localGitignore), 0666)
+}
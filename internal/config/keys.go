@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Keys address values in the config file by their JSON path separated by
+// dots, e.g. "instruments.stocks.compression.after" or
+// "instruments.stocks.aggregates.0.table".
+
+// Get returns the value of the key, objects and arrays are returned as JSON.
+func Get(key string) (string, error) {
+	document, err := readDocument()
+	if err != nil {
+		return "", err
+	}
+
+	value := document
+	for _, segment := range splitKey(key) {
+		value, err = child(value, segment)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Key '%s' %v", key, err))
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Set assigns the value to the key and creates missing objects on the way.
+// Values that are valid JSON are stored as such, everything else as string.
+// Passwords of postgresUrl are stored encrypted instead.
+func Set(key, value string) error {
+	if strings.Join(splitKey(key), ".") == "postgresUrl" {
+		profile, err := ActiveProfile()
+		if err != nil {
+			return err
+		}
+
+		value, err = storePassword(value, profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var parsedValue any
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if decoder.Decode(&parsedValue) != nil || decoder.More() {
+		parsedValue = value
+	}
+
+	return updateDocument(key, func(parent any, segment string) error {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[segment] = parsedValue
+		case []any:
+			index, err := arrayIndex(p, segment)
+			if err != nil {
+				return err
+			}
+			p[index] = parsedValue
+		}
+		return nil
+	})
+}
+
+// Unset removes the key from the config.
+func Unset(key string) error {
+	return updateDocument(key, func(parent any, segment string) error {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[segment]; !ok {
+				return errors.New("is not set")
+			}
+			delete(p, segment)
+		case []any:
+			return errors.New(
+				"is an array item, please set the whole array instead",
+			)
+		}
+		return nil
+	})
+}
+
+// ConfigFilePath returns the path of the config file without creating it.
+func ConfigFilePath() (string, error) {
+	configDir, err := ConfigDir(false)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(configDir, "config.json"), nil
+}
+
+// WriteConfigFile validates the content and replaces the config file with it.
+func WriteConfigFile(content []byte) error {
+	err := Validate(content)
+	if err != nil {
+		return err
+	}
+
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configFilePath, content, 0666)
+}
+
+func updateDocument(
+	key string,
+	update func(parent any, segment string) error,
+) error {
+	segments := splitKey(key)
+	if len(segments) == 0 {
+		return errors.New("Please provide a key, e.g. 'reportingCurrency'")
+	}
+
+	document, err := readDocument()
+	if err != nil {
+		return err
+	}
+
+	parent := document
+	for _, segment := range segments[:len(segments)-1] {
+		next, err := child(parent, segment)
+		if err != nil {
+			object, ok := parent.(map[string]any)
+			if !ok {
+				return errors.New(fmt.Sprintf("Key '%s' %v", key, err))
+			}
+			next = make(map[string]any)
+			object[segment] = next
+		}
+		parent = next
+	}
+
+	switch parent.(type) {
+	case map[string]any, []any:
+	default:
+		return errors.New(fmt.Sprintf(
+			"Key '%s' is nested in a value that is no object",
+			key,
+		))
+	}
+
+	err = update(parent, segments[len(segments)-1])
+	if err != nil {
+		return errors.New(fmt.Sprintf("Key '%s' %v", key, err))
+	}
+
+	content, err := jsonPrettyPrint(document)
+	if err != nil {
+		return err
+	}
+
+	return WriteConfigFile(content)
+}
+
+func readDocument() (any, error) {
+	content, err := readConfigContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var document any
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err = decoder.Decode(&document)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("The config is no valid JSON: %v", err))
+	}
+	if _, ok := document.(map[string]any); !ok {
+		return nil, errors.New("The config needs to be a JSON object")
+	}
+
+	return document, nil
+}
+
+func splitKey(key string) []string {
+	var segments []string
+	for _, segment := range strings.Split(key, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func child(value any, segment string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		next, ok := v[segment]
+		if !ok {
+			return nil, errors.New("is not set")
+		}
+		return next, nil
+	case []any:
+		index, err := arrayIndex(v, segment)
+		if err != nil {
+			return nil, err
+		}
+		return v[index], nil
+	default:
+		return nil, errors.New("is nested in a value that is no object")
+	}
+}
+
+func arrayIndex(array []any, segment string) (int, error) {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 || index >= len(array) {
+		return 0, errors.New(fmt.Sprintf(
+			"needs an index between 0 and %d instead of '%s'",
+			len(array)-1,
+			segment,
+		))
+	}
+	return index, nil
+}
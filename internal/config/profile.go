@@ -0,0 +1,292 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/premia-ai/cli/internal/credentials"
+)
+
+// DefaultProfile keeps its config directly in the .premia directory so that
+// setups from before profiles existed keep working.
+const DefaultProfile = "default"
+
+const profilesDir = "profiles"
+
+// activeProfileFile stores the profile that was selected with 'premia
+// profile use'
+const activeProfileFile = "profile"
+
+var profileFlag string
+
+var profileNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// SetProfile selects the profile for the current command, it takes precedence
+// over PREMIA_PROFILE and the profile selected with 'premia profile use'.
+func SetProfile(profile string) {
+	profileFlag = profile
+}
+
+// ActiveProfile returns the profile set by --profile, PREMIA_PROFILE or
+// 'premia profile use' in this order and the default profile otherwise.
+func ActiveProfile() (string, error) {
+	profile := profileFlag
+	if profile == "" {
+		profile = os.Getenv("PREMIA_PROFILE")
+	}
+	if profile == "" {
+		premiaDir, err := PremiaDir()
+		if err != nil {
+			return "", err
+		}
+
+		content, err := os.ReadFile(path.Join(premiaDir, activeProfileFile))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		profile = strings.TrimSpace(string(content))
+	}
+	if profile == "" {
+		return DefaultProfile, nil
+	}
+
+	return profile, validateProfileName(profile)
+}
+
+// Profiles returns the names of all profiles that have been created.
+func Profiles() ([]string, error) {
+	profiles := []string{DefaultProfile}
+
+	premiaDir, err := PremiaDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(path.Join(premiaDir, profilesDir))
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultProfile {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	sort.Strings(profiles[1:])
+
+	return profiles, nil
+}
+
+// CreateProfile sets up the directories and the config of a new profile.
+func CreateProfile(profile, postgresUrl string) error {
+	err := validateProfileName(profile)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	for _, existingProfile := range profiles {
+		if existingProfile == profile && profile != DefaultProfile {
+			return errors.New(
+				fmt.Sprintf("Profile '%s' already exists", profile),
+			)
+		}
+	}
+
+	previousProfile := profileFlag
+	SetProfile(profile)
+	defer SetProfile(previousProfile)
+
+	_, err = SetupConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if postgresUrl == "" {
+		return nil
+	}
+
+	postgresUrl, err = storePassword(postgresUrl, profile)
+	if err != nil {
+		return err
+	}
+
+	return updateConfigData(func(configData *ConfigFileData) {
+		configData.PostgresUrl = postgresUrl
+	})
+}
+
+// UseProfile makes the profile the active one for all following commands.
+func UseProfile(profile string) error {
+	err := validateProfileName(profile)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, existingProfile := range profiles {
+		exists = exists || existingProfile == profile
+	}
+	if !exists {
+		return errors.New(fmt.Sprintf(
+			"Profile '%s' doesn't exist, create it with 'premia profile create %s'",
+			profile,
+			profile,
+		))
+	}
+
+	premiaDir, err := getDir("", true)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(
+		path.Join(premiaDir, activeProfileFile),
+		[]byte(profile+"\n"),
+		0666,
+	)
+}
+
+// PostgresUrl returns the database of the active profile and falls back to
+// the POSTGRES_URL environment variable.
+func PostgresUrl() (string, error) {
+	configData, err := existingConfig()
+	if err != nil {
+		return "", err
+	}
+	if configData != nil && configData.PostgresUrl != "" {
+		return withPassword(configData.PostgresUrl)
+	}
+
+	postgresUrl := os.Getenv("POSTGRES_URL")
+	if postgresUrl == "" {
+		return "", errors.New(
+			"Please set POSTGRES_URL environment variable or the profile's database with 'premia config set postgresUrl <url>'",
+		)
+	}
+
+	return withPassword(postgresUrl)
+}
+
+// existingConfig returns the config of the active profile or nil if it
+// hasn't been created yet.
+func existingConfig() (*ConfigFileData, error) {
+	configFilePath, err := ConfigFilePath()
+	if err == nil {
+		_, err = os.Stat(configFilePath)
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	return Config()
+}
+
+// withPassword adds the stored database password to URLs without one, so
+// that passwords don't need to be written into the config.
+func withPassword(postgresUrl string) (string, error) {
+	parsedUrl, err := url.Parse(postgresUrl)
+	if err != nil {
+		return "", errors.New(
+			fmt.Sprintf("The database URL is invalid: %v", err),
+		)
+	}
+
+	if parsedUrl.User == nil {
+		return postgresUrl, nil
+	}
+	if _, hasPassword := parsedUrl.User.Password(); hasPassword {
+		return postgresUrl, nil
+	}
+
+	password, err := PostgresPassword()
+	if err != nil {
+		return "", err
+	}
+	// pgx falls back to ~/.pgpass
+	if password == "" {
+		return postgresUrl, nil
+	}
+
+	parsedUrl.User = url.UserPassword(parsedUrl.User.Username(), password)
+	return parsedUrl.String(), nil
+}
+
+// PostgresPassword returns the stored database password of the active profile
+// or an empty value if there is none.
+func PostgresPassword() (string, error) {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+
+	return credentials.Stored(
+		credentials.ForProfile(credentials.Postgres, profile),
+	)
+}
+
+// storePassword moves the password of the database URL into the encrypted
+// credentials of the profile and returns the URL without it, so that it isn't
+// written into the config.
+func storePassword(postgresUrl, profile string) (string, error) {
+	parsedUrl, err := url.Parse(postgresUrl)
+	if err != nil {
+		return "", errors.New(
+			fmt.Sprintf("The database URL is invalid: %v", err),
+		)
+	}
+
+	if parsedUrl.User == nil {
+		return postgresUrl, nil
+	}
+	password, hasPassword := parsedUrl.User.Password()
+	if !hasPassword {
+		return postgresUrl, nil
+	}
+
+	err = credentials.Set(
+		credentials.ForProfile(credentials.Postgres, profile),
+		password,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	parsedUrl.User = url.User(parsedUrl.User.Username())
+	return parsedUrl.String(), nil
+}
+
+func profileDirPath(profile string) string {
+	if profile == DefaultProfile {
+		return ""
+	}
+
+	return path.Join(profilesDir, profile)
+}
+
+func validateProfileName(profile string) error {
+	if !profileNameRegex.MatchString(profile) {
+		return errors.New(fmt.Sprintf(
+			"Profile name '%s' may only contain lowercase letters, digits, '-' and '_'",
+			profile,
+		))
+	}
+
+	return nil
+}
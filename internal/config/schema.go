@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/premia-ai/cli/resource"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Validate checks the content of a config file against the JSON Schema of
+// ConfigFileData.
+func Validate(content []byte) error {
+	schemaContent, err := resource.Fs.ReadFile(resource.ConfigSchemaPath)
+	if err != nil {
+		return err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	err = compiler.AddResource(
+		resource.ConfigSchemaPath,
+		bytes.NewReader(schemaContent),
+	)
+	if err != nil {
+		return err
+	}
+	schema, err := compiler.Compile(resource.ConfigSchemaPath)
+	if err != nil {
+		return err
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("The config is no valid JSON: %v", err))
+	}
+
+	err = schema.Validate(value)
+	if err != nil {
+		var validationError *jsonschema.ValidationError
+		if errors.As(err, &validationError) {
+			return errors.New(fmt.Sprintf(
+				"The config is invalid:\n%s",
+				formatValidationError(validationError),
+			))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// formatValidationError lists the leaf errors with the location of the value
+// in the config, e.g. "/instruments/stocks/timespan".
+func formatValidationError(validationError *jsonschema.ValidationError) string {
+	if len(validationError.Causes) == 0 {
+		location := validationError.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return fmt.Sprintf("  %s: %s", location, validationError.Message)
+	}
+
+	var lines []string
+	for _, cause := range validationError.Causes {
+		lines = append(lines, formatValidationError(cause))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Tables returns the tables and views that the config references for the
+// instrument type, dependent views come after the tables they are based on.
+func (i InstrumentConfig) Tables(instrumentType InstrumentType) []string {
+	tables := []string{i.BaseTable}
+	if i.AdjustedTable != "" {
+		tables = append(tables, i.AdjustedTable)
+	}
+	tables = append(tables, i.ConvertedTables...)
+	for _, aggregate := range i.Aggregates {
+		tables = append(tables, aggregate.Table)
+	}
+	for _, feature := range i.Features {
+		tables = append(tables, feature.View(instrumentType))
+	}
+
+	return tables
+}
+
+// featureViewSuffixes maps feature templates to the suffix of their view if
+// it differs from the template's name.
+var featureViewSuffixes = map[string]string{
+	"moving_averages": "averages",
+}
+
+// View returns the name of the view that the feature's template creates.
+// Moving averages also contain their window and whether they are based on
+// adjusted prices, so that several of them can exist side by side.
+func (f *FeatureConfig) View(instrumentType InstrumentType) string {
+	if f.ViewName != "" {
+		return f.ViewName
+	}
+
+	suffix, ok := featureViewSuffixes[f.Name]
+	if !ok {
+		suffix = f.Name
+	}
+
+	view := fmt.Sprintf(
+		"%s_%d_%s_%s",
+		instrumentType,
+		f.Quantity,
+		f.TimespanUnit,
+		suffix,
+	)
+	if f.Name == "moving_averages" {
+		view = fmt.Sprintf("%s_%d", view, f.WindowSize())
+		if strings.HasSuffix(f.ReferenceTable, "_adjusted") {
+			view += "_adjusted"
+		}
+	}
+
+	return view
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// CurrentVersion is the version of the config that this CLI writes. Every
+// change to ConfigFileData that older CLIs can't read or whose defaults
+// differ needs a new version and an upgrade step.
+const CurrentVersion = "3"
+
+type upgradeStep struct {
+	from    string
+	to      string
+	upgrade func(document map[string]any) error
+}
+
+// upgradeSteps are applied in order until the config has the current version.
+var upgradeSteps = []upgradeStep{
+	{from: "1", to: "2", upgrade: upgradeV1ToV2},
+	{from: "2", to: "3", upgrade: upgradeV2ToV3},
+}
+
+// upgradeV1ToV2 makes the defaults of configs written before bar quantities,
+// feature windows and volume types existed explicit.
+func upgradeV1ToV2(document map[string]any) error {
+	instruments, _ := document["instruments"].(map[string]any)
+	for _, value := range instruments {
+		instrument, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if _, ok := instrument["quantity"]; !ok {
+			instrument["quantity"] = 1
+		}
+		// Tables created before the volume type was configurable store
+		// volumes as INT
+		if _, ok := instrument["volumeType"]; !ok {
+			instrument["volumeType"] = VolumeInt
+		}
+
+		features, _ := instrument["features"].([]any)
+		for _, value := range features {
+			feature, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			quantity, ok := feature["quantity"]
+			if _, hasWindow := feature["window"]; ok && !hasWindow {
+				feature["window"] = quantity
+			}
+		}
+	}
+
+	return nil
+}
+
+// upgradeV2ToV3 keeps the names of moving average views that were created
+// before the window and the adjusted prices became part of the name.
+func upgradeV2ToV3(document map[string]any) error {
+	instruments, _ := document["instruments"].(map[string]any)
+	for instrumentType, value := range instruments {
+		instrument, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		features, _ := instrument["features"].([]any)
+		for _, value := range features {
+			feature, ok := value.(map[string]any)
+			if !ok || feature["name"] != "moving_averages" {
+				continue
+			}
+			if _, ok := feature["view"]; ok {
+				continue
+			}
+
+			feature["view"] = fmt.Sprintf(
+				"%s_%v_%v_averages",
+				instrumentType,
+				feature["quantity"],
+				feature["timespan"],
+			)
+		}
+	}
+
+	return nil
+}
+
+// readConfigContent reads the config file and upgrades it to the current
+// version first if it was written by an older CLI.
+func readConfigContent() ([]byte, error) {
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	upgradedContent, fromVersion, err := upgrade(content)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion == CurrentVersion {
+		return content, nil
+	}
+
+	backupPath := path.Join(
+		path.Dir(configFilePath),
+		fmt.Sprintf(
+			"config.v%s.%s.json.bak",
+			fromVersion,
+			time.Now().Format("20060102150405"),
+		),
+	)
+	err = os.WriteFile(backupPath, content, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	err = WriteConfigFile(upgradedContent)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"Upgrading the config from version %s to %s failed: %v",
+			fromVersion,
+			CurrentVersion,
+			err,
+		))
+	}
+
+	fmt.Fprintf(
+		os.Stderr,
+		"Upgraded config from version %s to %s, the old config was saved to '%s'.\n",
+		fromVersion,
+		CurrentVersion,
+		backupPath,
+	)
+
+	return upgradedContent, nil
+}
+
+// upgrade applies the upgrade steps to the content and returns the upgraded
+// content together with the version that the content had.
+func upgrade(content []byte) ([]byte, string, error) {
+	var document map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, "", errors.New(
+			fmt.Sprintf("The config is no valid JSON: %v", err),
+		)
+	}
+
+	fromVersion, _ := document["version"].(string)
+	if fromVersion == "" {
+		// Configs without a version were written by the first CLIs
+		fromVersion = "1"
+	}
+	if fromVersion == CurrentVersion {
+		return content, fromVersion, nil
+	}
+
+	version := fromVersion
+	for _, step := range upgradeSteps {
+		if step.from != version {
+			continue
+		}
+
+		err = step.upgrade(document)
+		if err != nil {
+			return nil, "", err
+		}
+		version = step.to
+	}
+	if version != CurrentVersion {
+		return nil, "", errors.New(fmt.Sprintf(
+			"Config version '%s' is not supported by this version of premia, please update premia",
+			fromVersion,
+		))
+	}
+	document["version"] = CurrentVersion
+
+	upgradedContent, err := jsonPrettyPrint(document)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return upgradedContent, fromVersion, nil
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		fromVersion string
+		expected    string
+	}{
+		{
+			name: "backfills quantity and volume type",
+			content: `{
+				"instruments": {
+					"stocks": {"baseTable": "stocks_1_minute_candles", "timespan": "minute"}
+				}
+			}`,
+			fromVersion: "1",
+			expected: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_1_minute_candles",
+						"timespan": "minute",
+						"quantity": 1,
+						"volumeType": "INT"
+					}
+				}
+			}`,
+		},
+		{
+			name: "keeps quantity and volume type",
+			content: `{
+				"version": "1",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_5_minute_candles",
+						"timespan": "minute",
+						"quantity": 5,
+						"volumeType": "NUMERIC"
+					}
+				}
+			}`,
+			fromVersion: "1",
+			expected: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_5_minute_candles",
+						"timespan": "minute",
+						"quantity": 5,
+						"volumeType": "NUMERIC"
+					}
+				}
+			}`,
+		},
+		{
+			name: "uses the feature quantity as window",
+			content: `{
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_1_minute_candles",
+						"timespan": "minute",
+						"quantity": 1,
+						"volumeType": "INT",
+						"features": [
+							{
+								"name": "moving_averages",
+								"timespan": "minute",
+								"quantity": 5,
+								"referenceTable": "stocks_1_minute_candles"
+							},
+							{
+								"name": "returns",
+								"timespan": "minute",
+								"quantity": 1,
+								"referenceTable": "stocks_1_minute_candles",
+								"window": 3
+							}
+						]
+					}
+				}
+			}`,
+			fromVersion: "1",
+			expected: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_1_minute_candles",
+						"timespan": "minute",
+						"quantity": 1,
+						"volumeType": "INT",
+						"features": [
+							{
+								"name": "moving_averages",
+								"timespan": "minute",
+								"quantity": 5,
+								"referenceTable": "stocks_1_minute_candles",
+								"window": 5,
+								"view": "stocks_5_minute_averages"
+							},
+							{
+								"name": "returns",
+								"timespan": "minute",
+								"quantity": 1,
+								"referenceTable": "stocks_1_minute_candles",
+								"window": 3
+							}
+						]
+					}
+				}
+			}`,
+		},
+		{
+			name: "keeps the names of moving average views",
+			content: `{
+				"version": "2",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_1_minute_candles",
+						"timespan": "minute",
+						"quantity": 1,
+						"volumeType": "INT",
+						"features": [
+							{
+								"name": "moving_averages",
+								"timespan": "minute",
+								"quantity": 1,
+								"referenceTable": "stocks_1_minute_candles_adjusted",
+								"window": 20
+							}
+						]
+					}
+				}
+			}`,
+			fromVersion: "2",
+			expected: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {
+						"baseTable": "stocks_1_minute_candles",
+						"timespan": "minute",
+						"quantity": 1,
+						"volumeType": "INT",
+						"features": [
+							{
+								"name": "moving_averages",
+								"timespan": "minute",
+								"quantity": 1,
+								"referenceTable": "stocks_1_minute_candles_adjusted",
+								"window": 20,
+								"view": "stocks_1_minute_averages"
+							}
+						]
+					}
+				}
+			}`,
+		},
+		{
+			name: "leaves current configs unchanged",
+			content: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {"baseTable": "stocks_1_minute_candles", "timespan": "minute"}
+				}
+			}`,
+			fromVersion: "3",
+			expected: `{
+				"version": "3",
+				"instruments": {
+					"stocks": {"baseTable": "stocks_1_minute_candles", "timespan": "minute"}
+				}
+			}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, fromVersion, err := upgrade([]byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fromVersion != test.fromVersion {
+				t.Errorf("fromVersion = %q, want %q", fromVersion, test.fromVersion)
+			}
+			assertJSONEqual(t, content, []byte(test.expected))
+		})
+	}
+}
+
+func TestUpgradeUnsupportedVersion(t *testing.T) {
+	_, _, err := upgrade([]byte(`{"version": "99", "instruments": {}}`))
+	if err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
+
+func TestReadConfigContent(t *testing.T) {
+	backupPattern := regexp.MustCompile(`^config\.v1\.\d{14}\.json\.bak$`)
+
+	tests := []struct {
+		name       string
+		content    string
+		wantBackup bool
+	}{
+		{
+			name: "backs up and upgrades version 1",
+			content: `{
+  "instruments": {
+    "stocks": {"baseTable": "stocks_1_minute_candles", "timespan": "minute"}
+  },
+  "backend": "duckdb"
+}
+`,
+			wantBackup: true,
+		},
+		{
+			name: "doesn't back up the current version",
+			content: `{
+  "version": "3",
+  "instruments": {
+    "stocks": {
+      "baseTable": "stocks_1_minute_candles",
+      "timespan": "minute",
+      "quantity": 1,
+      "volumeType": "INT"
+    }
+  },
+  "backend": "duckdb"
+}
+`,
+			wantBackup: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configDir := useTempPremiaDir(t)
+			configPath := path.Join(configDir, "config.json")
+			err := os.WriteFile(configPath, []byte(test.content), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := readConfigContent()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, _, err := upgrade([]byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, content, expected)
+
+			written, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, written, expected)
+
+			entries, err := os.ReadDir(configDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var backups []string
+			for _, entry := range entries {
+				if entry.Name() != "config.json" {
+					backups = append(backups, entry.Name())
+				}
+			}
+
+			if !test.wantBackup {
+				if len(backups) > 0 {
+					t.Fatalf("unexpected backups %v", backups)
+				}
+				return
+			}
+
+			if len(backups) != 1 || !backupPattern.MatchString(backups[0]) {
+				t.Fatalf("backups = %v, want one matching %s", backups, backupPattern)
+			}
+			backup, err := os.ReadFile(path.Join(configDir, backups[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(backup) != test.content {
+				t.Errorf("backup = %q, want the original config %q", backup, test.content)
+			}
+		})
+	}
+}
+
+// useTempPremiaDir switches to a temporary working directory with an empty
+// .premia directory, which the default profile uses, and returns it.
+func useTempPremiaDir(t *testing.T) string {
+	t.Helper()
+
+	workingDir := t.TempDir()
+	premiaDir := path.Join(workingDir, premiaDirName)
+	err := os.Mkdir(premiaDir, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previousDir) })
+
+	t.Setenv("HOME", workingDir)
+	t.Setenv("PREMIA_PROFILE", "")
+
+	return premiaDir
+}
+
+func assertJSONEqual(t *testing.T, actual, expected []byte) {
+	t.Helper()
+
+	var actualValue, expectedValue any
+	err := json.Unmarshal(actual, &actualValue)
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %v", actual, err)
+	}
+	err = json.Unmarshal(expected, &expectedValue)
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %v", expected, err)
+	}
+
+	if !reflect.DeepEqual(actualValue, expectedValue) {
+		t.Errorf("got %s, want %s", actual, expected)
+	}
+}
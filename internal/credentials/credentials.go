@@ -0,0 +1,419 @@
+package credentials
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+const (
+	Polygon    = "polygon"
+	TwelveData = "twelvedata"
+	// Postgres is the database password that is used if the database URL
+	// doesn't contain one. It's stored per profile, see ForProfile.
+	Postgres = "postgres"
+)
+
+// profileCredentials are stored once per profile since every profile can use
+// its own database, API keys are shared by all profiles.
+var profileCredentials = map[string]bool{
+	Postgres: true,
+}
+
+// defaultProfile matches config.DefaultProfile, its credentials keep the names
+// from before profiles existed.
+const defaultProfile = "default"
+
+// envVars are checked before the credentials file so that CI setups can
+// keep using environment variables.
+var envVars = map[string]string{
+	Polygon:    "POLYGON_API_KEY",
+	TwelveData: "TWELVEDATA_API_KEY",
+	Postgres:   "PGPASSWORD",
+}
+
+// Credentials are always stored in the home directory, never in project-local
+// .premia directories that might be committed. The key is only stored in
+// keyFile on systems without a keyring.
+const (
+	credentialsFile = ".premia/credentials"
+	keyFile         = ".premia/credentials.key"
+)
+
+const (
+	keyringService = "premia"
+	keyringUser    = "credentials-key"
+)
+
+type Credential struct {
+	// Name is qualified with the profile for credentials of other profiles
+	// than the default one, e.g. "postgres@research"
+	Name string
+	// Source is either the environment variable or "credentials file"
+	Source string
+}
+
+// Names returns the credentials that premia knows about.
+func Names() []string {
+	var names []string
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForProfile returns the name under which the credential is stored for the
+// profile. Names of credentials that are shared by all profiles are returned
+// unchanged.
+func ForProfile(name, profile string) string {
+	if !profileCredentials[name] || profile == defaultProfile {
+		return name
+	}
+
+	return name + "@" + profile
+}
+
+// Get returns the credential from its environment variable or the encrypted
+// credentials file.
+func Get(name string) (string, error) {
+	value, err := Stored(name)
+	if err != nil {
+		return "", err
+	}
+
+	if value == "" {
+		return "", errors.New(fmt.Sprintf(
+			"Please set %s environment variable or store it with 'premia auth set %s'",
+			envVars[baseName(name)],
+			baseName(name),
+		))
+	}
+
+	return value, nil
+}
+
+// Stored is like Get, but returns an empty value instead of an error if the
+// credential isn't set. Errors of the credentials file are still returned.
+func Stored(name string) (string, error) {
+	envVar, err := envVar(name)
+	if err != nil {
+		return "", err
+	}
+
+	if value := os.Getenv(envVar); value != "" {
+		return value, nil
+	}
+
+	values, err := read()
+	if err != nil {
+		return "", err
+	}
+
+	return values[name], nil
+}
+
+// Set stores the credential in the encrypted credentials file.
+func Set(name, value string) error {
+	_, err := envVar(name)
+	if err != nil {
+		return err
+	}
+
+	values, err := read()
+	if err != nil {
+		return err
+	}
+
+	values[name] = value
+	return write(values)
+}
+
+// SetForProcess makes the credential available to the running command only.
+func SetForProcess(name, value string) error {
+	envVar, err := envVar(name)
+	if err != nil {
+		return err
+	}
+
+	return os.Setenv(envVar, value)
+}
+
+// Remove deletes the credential from the encrypted credentials file.
+func Remove(name string) error {
+	values, err := read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values[name]; !ok {
+		return errors.New(
+			fmt.Sprintf("There is no stored credential '%s'", name),
+		)
+	}
+
+	delete(values, name)
+	return write(values)
+}
+
+// List returns the credentials that are available without their values.
+func List() ([]Credential, error) {
+	values, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	var storedNames []string
+	for name := range values {
+		storedNames = append(storedNames, name)
+	}
+	sort.Strings(storedNames)
+
+	var credentials []Credential
+	for _, name := range Names() {
+		if os.Getenv(envVars[name]) != "" {
+			credentials = append(credentials, Credential{
+				Name:   name,
+				Source: envVars[name],
+			})
+			continue
+		}
+
+		for _, storedName := range storedNames {
+			if baseName(storedName) == name {
+				credentials = append(credentials, Credential{
+					Name:   storedName,
+					Source: "credentials file",
+				})
+			}
+		}
+	}
+
+	return credentials, nil
+}
+
+// ReadSecret asks the question and reads the answer without echoing it if
+// stdin is a terminal.
+func ReadSecret(question string) (string, error) {
+	fmt.Printf(question + "\n\n>> ")
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		value, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if scanner.Err() != nil {
+		return "", scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+func envVar(name string) (string, error) {
+	envVar, ok := envVars[baseName(name)]
+	if !ok {
+		return "", errors.New(fmt.Sprintf(
+			"Unknown credential '%s', the supported credentials are %s",
+			name,
+			strings.Join(Names(), ", "),
+		))
+	}
+
+	return envVar, nil
+}
+
+// baseName removes the profile from names returned by ForProfile.
+func baseName(name string) string {
+	baseName, _, _ := strings.Cut(name, "@")
+	return baseName
+}
+
+func filePath(file string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, file), nil
+}
+
+func read() (map[string]string, error) {
+	values := make(map[string]string)
+
+	credentialsPath, err := filePath(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := os.ReadFile(credentialsPath)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New(fmt.Sprintf("'%s' is corrupted", credentialsPath))
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"'%s' cannot be decrypted with its key: %v",
+			credentialsPath,
+			err,
+		))
+	}
+
+	err = json.Unmarshal(plaintext, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func write(values map[string]string) error {
+	credentialsPath, err := filePath(credentialsFile)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(path.Dir(credentialsPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(true)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+	err = os.WriteFile(credentialsPath, ciphertext, 0600)
+	if err != nil {
+		return err
+	}
+
+	// WriteFile keeps the permissions of existing files
+	return os.Chmod(credentialsPath, 0600)
+}
+
+// newGCM reads the AES-256 key of the credentials file and creates it first
+// if it doesn't exist and createIfMissing is set.
+func newGCM(createIfMissing bool) (cipher.AEAD, error) {
+	key, err := readKey(createIfMissing)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New(
+			fmt.Sprintf("The key of the credentials file is invalid: %v", err),
+		)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// readKey returns the key from the OS keyring and falls back to keyFile if
+// there is no keyring. Keys of keyFile are moved into the keyring once it's
+// available and the credentials are written.
+func readKey(createIfMissing bool) ([]byte, error) {
+	keyPath, err := filePath(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedKey, err := keyring.Get(keyringService, keyringUser)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"The key of the credentials file in the keyring is invalid: %v",
+				err,
+			))
+		}
+		return key, nil
+	}
+	hasKeyring := err == keyring.ErrNotFound
+
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if hasKeyring && createIfMissing {
+			err = keyring.Set(
+				keyringService,
+				keyringUser,
+				base64.StdEncoding.EncodeToString(key),
+			)
+			if err == nil {
+				os.Remove(keyPath)
+			}
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !createIfMissing {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	_, err = io.ReadFull(rand.Reader, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasKeyring {
+		err = keyring.Set(
+			keyringService,
+			keyringUser,
+			base64.StdEncoding.EncodeToString(key),
+		)
+		if err == nil {
+			return key, nil
+		}
+	}
+
+	err = os.WriteFile(keyPath, key, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/premia-ai/cli/internal/config"
+)
+
+const defaultApplicationName = "premia"
+
+var pool *pgxpool.Pool
+
+// Pool returns the connection pool of the active profile. It's created on the
+// first call so that all migrations, imports and queries of a command share
+// its connections.
+func Pool() (*pgxpool.Pool, error) {
+	if pool != nil {
+		return pool, nil
+	}
+
+	backend, err := config.Backend()
+	if err != nil {
+		return nil, err
+	}
+	if backend == config.BackendDuckDB {
+		return nil, errors.New(
+			"This command needs a Postgres database, but the profile uses the duckdb backend",
+		)
+	}
+
+	databaseConfig, err := config.Database()
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig, err := newPoolConfig(databaseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	newPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, describeError(err, databaseConfig, poolConfig)
+	}
+
+	// Connections are established lazily, so the pool is pinged to report
+	// misconfigurations before the first query
+	err = newPool.Ping(context.Background())
+	if err != nil {
+		newPool.Close()
+		return nil, describeError(err, databaseConfig, poolConfig)
+	}
+
+	pool = newPool
+	return pool, nil
+}
+
+// Close closes all connections of the pool and the DuckDB file.
+func Close() {
+	if d, ok := db.(*duckDB); ok {
+		d.db.Close()
+	}
+	db = nil
+
+	if pool != nil {
+		pool.Close()
+		pool = nil
+	}
+}
+
+// newPoolConfig applies the database settings of the config to the
+// postgresUrl or builds the connection from the settings alone if there is
+// no URL.
+func newPoolConfig(
+	databaseConfig *config.DatabaseConfig,
+) (*pgxpool.Config, error) {
+	postgresUrl, err := config.PostgresUrl()
+	if err != nil {
+		if databaseConfig.Host == "" {
+			return nil, err
+		}
+		postgresUrl = "postgres://"
+	}
+
+	parsedUrl, err := url.Parse(postgresUrl)
+	if err != nil {
+		return nil, errors.New(
+			fmt.Sprintf("The database URL is invalid: %v", err),
+		)
+	}
+
+	if databaseConfig.Host != "" || databaseConfig.Port != 0 {
+		host, port := parsedUrl.Hostname(), parsedUrl.Port()
+		if databaseConfig.Host != "" {
+			host = databaseConfig.Host
+		}
+		if databaseConfig.Port != 0 {
+			port = strconv.Itoa(databaseConfig.Port)
+		}
+		parsedUrl.Host = host
+		if port != "" {
+			parsedUrl.Host = net.JoinHostPort(host, port)
+		}
+	}
+
+	if databaseConfig.Name != "" {
+		parsedUrl.Path = "/" + databaseConfig.Name
+	}
+
+	if databaseConfig.User != "" {
+		password, hasPassword := "", false
+		if parsedUrl.User != nil {
+			password, hasPassword = parsedUrl.User.Password()
+		}
+		if !hasPassword {
+			// pgx falls back to ~/.pgpass if there is no stored password
+			password, err = config.PostgresPassword()
+			if err != nil {
+				return nil, err
+			}
+			hasPassword = password != ""
+		}
+
+		parsedUrl.User = url.User(databaseConfig.User)
+		if hasPassword {
+			parsedUrl.User = url.UserPassword(databaseConfig.User, password)
+		}
+	}
+
+	query := parsedUrl.Query()
+	if databaseConfig.SslMode != "" {
+		query.Set("sslmode", databaseConfig.SslMode)
+	}
+	for key, file := range map[string]string{
+		"sslRootCert": databaseConfig.SslRootCert,
+		"sslCert":     databaseConfig.SslCert,
+		"sslKey":      databaseConfig.SslKey,
+	} {
+		if file == "" {
+			continue
+		}
+
+		_, err = os.Stat(file)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"database.%s '%s' cannot be read: %v",
+				key,
+				file,
+				err,
+			))
+		}
+		query.Set(strings.ToLower(key), file)
+	}
+	parsedUrl.RawQuery = query.Encode()
+
+	poolConfig, err := pgxpool.ParseConfig(parsedUrl.String())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"The database settings are invalid, check postgresUrl and database: %v",
+			err,
+		))
+	}
+
+	if databaseConfig.StatementTimeout != "" {
+		timeout, err := time.ParseDuration(databaseConfig.StatementTimeout)
+		if err != nil || timeout < 0 {
+			return nil, errors.New(fmt.Sprintf(
+				"database.statementTimeout '%s' needs to be a duration like '30s' or '5m'",
+				databaseConfig.StatementTimeout,
+			))
+		}
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(
+			timeout.Milliseconds(),
+			10,
+		)
+	}
+
+	if databaseConfig.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = databaseConfig.ApplicationName
+	} else if poolConfig.ConnConfig.RuntimeParams["application_name"] == "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = defaultApplicationName
+	}
+
+	if databaseConfig.MaxConnections != 0 {
+		poolConfig.MaxConns = int32(databaseConfig.MaxConnections)
+	}
+
+	return poolConfig, nil
+}
+
+// describeError names the setting that most likely caused the connection
+// error.
+func describeError(
+	err error,
+	databaseConfig *config.DatabaseConfig,
+	poolConfig *pgxpool.Config,
+) error {
+	connConfig := poolConfig.ConnConfig
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		// invalid_password and invalid_authorization_specification
+		case "28P01", "28000":
+			return errors.New(fmt.Sprintf(
+				"The database rejected user '%s', check %s and the password stored with 'premia auth set postgres': %v",
+				connConfig.User,
+				setting("user", databaseConfig.User != ""),
+				err,
+			))
+		// invalid_catalog_name
+		case "3D000":
+			return errors.New(fmt.Sprintf(
+				"Database '%s' doesn't exist, check %s: %v",
+				connConfig.Database,
+				setting("name", databaseConfig.Name != ""),
+				err,
+			))
+		// too_many_connections
+		case "53300":
+			return errors.New(fmt.Sprintf(
+				"The database has no free connections, lower database.maxConnections: %v",
+				err,
+			))
+		}
+	}
+
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	if errors.As(err, &unknownAuthorityErr) {
+		return errors.New(fmt.Sprintf(
+			"The database's certificate is not signed by a trusted authority, check database.sslRootCert: %v",
+			err,
+		))
+	}
+	if errors.As(err, &hostnameErr) {
+		return errors.New(fmt.Sprintf(
+			"The database's certificate doesn't match host '%s', check %s or use database.sslMode 'verify-ca': %v",
+			connConfig.Host,
+			setting("host", databaseConfig.Host != ""),
+			err,
+		))
+	}
+	if errors.As(err, &certificateErr) ||
+		errors.As(err, &recordHeaderErr) ||
+		strings.Contains(err.Error(), "tls: ") ||
+		strings.Contains(err.Error(), "refused TLS") {
+		return errors.New(fmt.Sprintf(
+			"The TLS connection to the database failed, check database.sslMode, database.sslRootCert, database.sslCert and database.sslKey: %v",
+			err,
+		))
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return errors.New(fmt.Sprintf(
+			"Database host '%s' cannot be resolved, check %s: %v",
+			connConfig.Host,
+			setting("host", databaseConfig.Host != ""),
+			err,
+		))
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, context.DeadlineExceeded) {
+		return errors.New(fmt.Sprintf(
+			"Database at '%s' is not reachable, check %s and %s: %v",
+			net.JoinHostPort(connConfig.Host, strconv.Itoa(int(connConfig.Port))),
+			setting("host", databaseConfig.Host != ""),
+			setting("port", databaseConfig.Port != 0),
+			err,
+		))
+	}
+
+	return errors.New(fmt.Sprintf("Unable to connect to database: %v", err))
+}
+
+// setting returns how the setting is named in the config file or the
+// database URL.
+func setting(key string, isConfigured bool) string {
+	if isConfigured {
+		return "database." + key
+	}
+
+	return "the " + key + " of the database URL"
+}
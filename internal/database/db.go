@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/premia-ai/cli/internal/config"
+)
+
+// DB is the database of the active profile. Imports and queries that work
+// with every backend use it, features that need TimescaleDB or Postgres use
+// Pool directly.
+type DB interface {
+	// Backend is one of the config.Backend constants
+	Backend() string
+	Exec(ctx context.Context, sql string, args ...any) error
+	QueryRow(ctx context.Context, sql string, args ...any) Row
+	Query(ctx context.Context, sql string, args ...any) (Rows, error)
+	// Upsert inserts the rows and overwrites existing rows that have the
+	// same values in the conflict columns
+	Upsert(
+		ctx context.Context,
+		table string,
+		columnNames []string,
+		conflictColumnNames []string,
+		rows pgx.CopyFromSource,
+	) error
+	// CopyCsv loads a CSV file with a header row into the table
+	CopyCsv(ctx context.Context, table, filePath string) error
+	// ExportCsv writes all rows of the table to a CSV file with a header row
+	// that CopyCsv can load again
+	ExportCsv(ctx context.Context, table, filePath string) error
+	// TableExists also returns true for views and materialized views
+	TableExists(ctx context.Context, table string) (bool, error)
+	// ExecRolledBack runs the statements in a transaction that is rolled back
+	// afterwards, e.g. to check permissions without changing the database
+	ExecRolledBack(ctx context.Context, statements ...string) error
+}
+
+type Row interface {
+	Scan(dest ...any) error
+}
+
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close()
+}
+
+var db DB
+
+// Open returns the database of the active profile. Like the pool it's opened
+// on the first call and shared by all following calls.
+func Open() (DB, error) {
+	if db != nil {
+		return db, nil
+	}
+
+	backend, err := config.Backend()
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == config.BackendDuckDB {
+		duckDB, err := openDuckDB()
+		if err != nil {
+			return nil, err
+		}
+		db = duckDB
+		return db, nil
+	}
+
+	pool, err := Pool()
+	if err != nil {
+		return nil, err
+	}
+
+	db = &postgresDB{pool: pool, backend: backend}
+	return db, nil
+}
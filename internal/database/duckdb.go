@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/marcboeker/go-duckdb"
+
+	"github.com/premia-ai/cli/internal/config"
+)
+
+// duckDB stores the data of the duckdb backend in a local file.
+type duckDB struct {
+	db *sql.DB
+}
+
+func openDuckDB() (*duckDB, error) {
+	duckDBPath, err := config.DuckDBPath()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(path.Dir(duckDBPath), 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := duckdb.NewConnector(duckDBPath, initDuckDBConn)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"database.path '%s' cannot be opened: %v",
+			duckDBPath,
+			err,
+		))
+	}
+
+	sqlDB := sql.OpenDB(connector)
+	// Connections are initialized lazily, so problems with the file or the
+	// icu extension are reported right away
+	err = sqlDB.Ping()
+	if err != nil {
+		sqlDB.Close()
+		return nil, errors.New(fmt.Sprintf(
+			"database.path '%s' cannot be opened: %v",
+			duckDBPath,
+			err,
+		))
+	}
+
+	return &duckDB{db: sqlDB}, nil
+}
+
+// initDuckDBConn loads the icu extension that provides timezones, e.g. to
+// bucket daily aggregates in the exchange's timezone. DuckDB downloads it
+// once on first use.
+func initDuckDBConn(execer driver.ExecerContext) error {
+	ctx := context.Background()
+	_, err := execer.ExecContext(ctx, "LOAD icu;", nil)
+	if err != nil {
+		_, err = execer.ExecContext(ctx, "INSTALL icu; LOAD icu;", nil)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"DuckDB's icu extension is needed for exchange timezones and could not be installed, please connect to the internet once: %v",
+			err,
+		))
+	}
+
+	// Casts between timestamps with and without timezone use UTC like
+	// Postgres sessions of premia
+	_, err = execer.ExecContext(ctx, "SET TimeZone = 'UTC';", nil)
+	return err
+}
+
+func (d *duckDB) Backend() string {
+	return config.BackendDuckDB
+}
+
+func (d *duckDB) Exec(ctx context.Context, sql string, args ...any) error {
+	_, err := d.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+func (d *duckDB) QueryRow(ctx context.Context, sql string, args ...any) Row {
+	return d.db.QueryRowContext(ctx, sql, args...)
+}
+
+func (d *duckDB) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	rows, err := d.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlRows{rows}, nil
+}
+
+// Upsert inserts the rows one by one in a transaction, DuckDB handles the
+// conflicts with the table's unique index itself.
+func (d *duckDB) Upsert(
+	ctx context.Context,
+	table string,
+	columnNames []string,
+	conflictColumnNames []string,
+	rows pgx.CopyFromSource,
+) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var placeholders []string
+	for i := range columnNames {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+
+	statement, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (%s)
+		VALUES (%s)
+		ON CONFLICT (%s) DO UPDATE SET %s;`,
+		pgx.Identifier{table}.Sanitize(),
+		strings.Join(columnNames, ", "),
+		strings.Join(placeholders, ", "),
+		strings.Join(conflictColumnNames, ", "),
+		conflictUpdates(columnNames, conflictColumnNames),
+	))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+
+		_, err = statement.ExecContext(ctx, values...)
+		if err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	return tx.Commit()
+}
+
+func (d *duckDB) CopyCsv(ctx context.Context, table, filePath string) error {
+	_, err := d.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"COPY %s FROM %s (FORMAT CSV, DELIMITER ',', HEADER);",
+			pgx.Identifier{table}.Sanitize(),
+			quoteLiteral(filePath),
+		),
+	)
+	return err
+}
+
+func (d *duckDB) ExportCsv(ctx context.Context, table, filePath string) error {
+	_, err := d.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"COPY (SELECT * FROM %s) TO %s (FORMAT CSV, DELIMITER ',', HEADER);",
+			pgx.Identifier{table}.Sanitize(),
+			quoteLiteral(filePath),
+		),
+	)
+	return err
+}
+
+func (d *duckDB) TableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	err := d.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM duckdb_tables() WHERE table_name = $1
+			UNION ALL
+			SELECT 1 FROM duckdb_views() WHERE view_name = $1
+		);`,
+		table,
+	).Scan(&exists)
+	return exists, err
+}
+
+// sqlRows adapts database/sql's rows to the Rows of pgx.
+type sqlRows struct {
+	*sql.Rows
+}
+
+func (r *sqlRows) Close() {
+	r.Rows.Close()
+}
+
+func (d *duckDB) ExecRolledBack(ctx context.Context, statements ...string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratedatabase "github.com/golang-migrate/migrate/v4/database"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// MigrationVersion reads the applied migration from schema_migrations. Unlike
+// Migrate it never creates the table, applied is false if no migration has
+// been applied yet.
+func MigrationVersion(db DB) (version uint, dirty bool, applied bool, err error) {
+	exists, err := db.TableExists(context.Background(), "schema_migrations")
+	if err != nil || !exists {
+		return 0, false, false, err
+	}
+
+	var storedVersion int64
+	err = db.QueryRow(
+		context.Background(),
+		"SELECT version, dirty FROM schema_migrations LIMIT 1;",
+	).Scan(&storedVersion, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+		return 0, false, false, nil
+	}
+	if err != nil {
+		return 0, false, false, err
+	}
+
+	return uint(storedVersion), dirty, true, nil
+}
+
+// Migrate returns the migrations of the directory for the database of the
+// active profile.
+func Migrate(migrationsPath string) (*migrate.Migrate, error) {
+	db, err := Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var driver migratedatabase.Driver
+	var driverName string
+	switch d := db.(type) {
+	case *duckDB:
+		driver, err = newDuckDBDriver(d.db)
+		driverName = "duckdb"
+	case *postgresDB:
+		// The migrations run on the shared pool so that they use the same
+		// TLS and timeout settings as all other queries
+		driver, err = migratepgx.WithInstance(
+			stdlib.OpenDBFromPool(d.pool),
+			&migratepgx.Config{},
+		)
+		driverName = "pgx5"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// file:// needs to be added otherwise the New method is throwing an error
+	return migrate.NewWithDatabaseInstance(
+		"file://"+migrationsPath,
+		driverName,
+		driver,
+	)
+}
+
+// duckDBDriver tracks the applied migrations in the same schema_migrations
+// table that golang-migrate's Postgres drivers use.
+type duckDBDriver struct {
+	db *sql.DB
+}
+
+func newDuckDBDriver(db *sql.DB) (*duckDBDriver, error) {
+	// Without a primary key since DuckDB rejects reinserting a deleted key
+	// in the same transaction
+	_, err := db.Exec(
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL,
+			dirty BOOLEAN NOT NULL
+		);`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &duckDBDriver{db: db}, nil
+}
+
+func (d *duckDBDriver) Open(url string) (migratedatabase.Driver, error) {
+	return nil, errors.New("DuckDB migrations can only run on an open database")
+}
+
+// Close keeps the database open since it's shared with other queries.
+func (d *duckDBDriver) Close() error {
+	return nil
+}
+
+// Lock is a no-op since DuckDB files can only be written by one process.
+func (d *duckDBDriver) Lock() error {
+	return nil
+}
+
+func (d *duckDBDriver) Unlock() error {
+	return nil
+}
+
+func (d *duckDBDriver) Run(migration io.Reader) error {
+	content, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(string(content))
+	return err
+}
+
+func (d *duckDBDriver) SetVersion(version int, dirty bool) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM schema_migrations;")
+	if err != nil {
+		return err
+	}
+
+	// Like the Postgres drivers no version is stored for a clean database
+	// without migrations
+	if version >= 0 || (version == migratedatabase.NilVersion && dirty) {
+		_, err = tx.Exec(
+			"INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2);",
+			version,
+			dirty,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d *duckDBDriver) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+	err := d.db.QueryRow(
+		"SELECT version, dirty FROM schema_migrations LIMIT 1;",
+	).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return migratedatabase.NilVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+// Drop removes all views, tables and macros of the main schema.
+func (d *duckDBDriver) Drop() error {
+	rows, err := d.db.Query(
+		`SELECT 'DROP VIEW IF EXISTS "' || view_name || '" CASCADE;'
+		FROM duckdb_views() WHERE NOT internal AND schema_name = 'main'
+		UNION ALL
+		SELECT 'DROP TABLE IF EXISTS "' || table_name || '" CASCADE;'
+		FROM duckdb_tables() WHERE NOT internal AND schema_name = 'main'
+		UNION ALL
+		SELECT DISTINCT 'DROP MACRO IF EXISTS "' || function_name || '";'
+		FROM duckdb_functions()
+		WHERE NOT internal AND function_type = 'macro' AND schema_name = 'main';`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var statements []string
+	for rows.Next() {
+		var statement string
+		err = rows.Scan(&statement)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		statements = append(statements, statement)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for _, statement := range statements {
+		_, err = d.db.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,340 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/premia-ai/cli/internal/config"
+)
+
+// postgresDB is used by the timescale and the postgres backend.
+type postgresDB struct {
+	pool    *pgxpool.Pool
+	backend string
+}
+
+func (p *postgresDB) Backend() string {
+	return p.backend
+}
+
+func (p *postgresDB) Exec(ctx context.Context, sql string, args ...any) error {
+	_, err := p.pool.Exec(ctx, sql, args...)
+	return err
+}
+
+func (p *postgresDB) QueryRow(ctx context.Context, sql string, args ...any) Row {
+	return p.pool.QueryRow(ctx, sql, args...)
+}
+
+func (p *postgresDB) Query(
+	ctx context.Context,
+	sql string,
+	args ...any,
+) (Rows, error) {
+	return p.pool.Query(ctx, sql, args...)
+}
+
+// Upsert copies the rows into a temporary staging table first so that rows
+// which already exist in the table get overwritten instead of violating the
+// unique index on the conflict columns.
+func (p *postgresDB) Upsert(
+	ctx context.Context,
+	table string,
+	columnNames []string,
+	conflictColumnNames []string,
+	rows pgx.CopyFromSource,
+) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	stagingTable := table + "_staging"
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TEMPORARY TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP;",
+		pgx.Identifier{stagingTable}.Sanitize(),
+		pgx.Identifier{table}.Sanitize(),
+	))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{stagingTable},
+		columnNames,
+		rows,
+	)
+	if err != nil {
+		return err
+	}
+
+	columns := strings.Join(columnNames, ", ")
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (%s)
+		SELECT %s FROM %s
+		ON CONFLICT (%s) DO UPDATE SET %s;`,
+		pgx.Identifier{table}.Sanitize(),
+		columns,
+		columns,
+		pgx.Identifier{stagingTable}.Sanitize(),
+		strings.Join(conflictColumnNames, ", "),
+		conflictUpdates(columnNames, conflictColumnNames),
+	))
+	if err != nil {
+		return err
+	}
+
+	err = p.maintainPartitions(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *postgresDB) CopyCsv(ctx context.Context, table, filePath string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The file is read by the database server, not by premia
+	_, err = tx.Exec(
+		ctx,
+		fmt.Sprintf(
+			"COPY %s FROM %s DELIMITER ',' CSV HEADER;",
+			pgx.Identifier{table}.Sanitize(),
+			quoteLiteral(filePath),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = p.maintainPartitions(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MaintainPartitions moves the rows of the default partition of the table
+// into yearly partitions, e.g. rows that were imported before their
+// partition existed. Tables of other backends and tables that aren't
+// partitioned are left unchanged.
+func MaintainPartitions(db DB, table string) error {
+	p, ok := db.(*postgresDB)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = p.maintainPartitions(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// maintainPartitions creates the missing yearly partitions of tables that the
+// add_candles and add_fx_rates templates of the postgres backend partition by
+// time. The templates only create partitions up to five years ahead, so rows
+// outside of them end up in the default partition and are moved into new
+// partitions here.
+func (p *postgresDB) maintainPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	table string,
+) error {
+	if p.backend != config.BackendPostgres {
+		return nil
+	}
+
+	defaultPartition := table + "_default"
+	var isPartitioned bool
+	err := tx.QueryRow(
+		ctx,
+		`SELECT EXISTS (
+			SELECT FROM pg_inherits
+			WHERE inhparent = to_regclass($1) AND inhrelid = to_regclass($2)
+		);`,
+		table,
+		defaultPartition,
+	).Scan(&isPartitioned)
+	if err != nil || !isPartitioned {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		`SELECT DISTINCT EXTRACT(YEAR FROM time AT TIME ZONE 'UTC')::int
+		FROM %s ORDER BY 1;`,
+		pgx.Identifier{defaultPartition}.Sanitize(),
+	))
+	if err != nil {
+		return err
+	}
+	var years []int
+	for rows.Next() {
+		var year int
+		err = rows.Scan(&year)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		years = append(years, year)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for _, year := range years {
+		partition := pgx.Identifier{fmt.Sprintf("%s_%d", table, year)}.Sanitize()
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(1, 0, 0)
+
+		// The rows need to leave the default partition before the new
+		// partition can be attached
+		statements := []string{
+			fmt.Sprintf(
+				"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS);",
+				partition,
+				pgx.Identifier{table}.Sanitize(),
+			),
+			fmt.Sprintf(
+				`WITH moved AS (
+					DELETE FROM %s WHERE time >= %s AND time < %s RETURNING *
+				)
+				INSERT INTO %s SELECT * FROM moved;`,
+				pgx.Identifier{defaultPartition}.Sanitize(),
+				quoteLiteral(from.Format(time.RFC3339)),
+				quoteLiteral(to.Format(time.RFC3339)),
+				partition,
+			),
+			fmt.Sprintf(
+				"ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s);",
+				pgx.Identifier{table}.Sanitize(),
+				partition,
+				quoteLiteral(from.Format(time.RFC3339)),
+				quoteLiteral(to.Format(time.RFC3339)),
+			),
+		}
+		for _, statement := range statements {
+			_, err = tx.Exec(ctx, statement)
+			if err != nil {
+				return errors.New(fmt.Sprintf(
+					"The partition of %d of '%s' cannot be created: %v",
+					year,
+					table,
+					err,
+				))
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExportCsv streams the rows to premia so that the file is written on the
+// machine of the user instead of the database server. Selecting the rows
+// instead of copying the table also includes the chunks of hypertables and
+// the partitions of partitioned tables.
+func (p *postgresDB) ExportCsv(ctx context.Context, table, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Conn().PgConn().CopyTo(
+		ctx,
+		file,
+		fmt.Sprintf(
+			"COPY (SELECT * FROM %s) TO STDOUT DELIMITER ',' CSV HEADER;",
+			pgx.Identifier{table}.Sanitize(),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (p *postgresDB) TableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	err := p.pool.QueryRow(
+		ctx,
+		"SELECT to_regclass($1) IS NOT NULL;",
+		table,
+	).Scan(&exists)
+	return exists, err
+}
+
+// conflictUpdates returns the SET clause that overwrites all columns except
+// the conflict columns with the values of the rejected row.
+func conflictUpdates(columnNames, conflictColumnNames []string) string {
+	var updates []string
+	for _, columnName := range columnNames {
+		isConflictColumn := false
+		for _, conflictColumnName := range conflictColumnNames {
+			isConflictColumn = isConflictColumn ||
+				columnName == conflictColumnName
+		}
+		if isConflictColumn {
+			continue
+		}
+
+		updates = append(
+			updates,
+			fmt.Sprintf("%s = EXCLUDED.%s", columnName, columnName),
+		)
+	}
+
+	return strings.Join(updates, ", ")
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (p *postgresDB) ExecRolledBack(ctx context.Context, statements ...string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, statement := range statements {
+		_, err = tx.Exec(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Capabilities struct {
+	// Timespans maps every supported timespan to its supported multipliers,
+	// a nil slice means that every positive multiplier is supported
+	Timespans map[Timespan][]int
+	// MaxHistory is how many years back data can be requested, 0 means that
+	// there is no limit
+	MaxHistory int
+	// Instruments are the supported instrument types, e.g. "stocks"
+	Instruments []string
+	// MultiTicker is true if several tickers can be imported at once
+	MultiTicker bool
+}
+
+var allTimespans = map[Timespan][]int{
+	Second:  nil,
+	Minute:  nil,
+	Hour:    nil,
+	Day:     nil,
+	Week:    nil,
+	Month:   nil,
+	Quarter: nil,
+	Year:    nil,
+}
+
+var ProviderCapabilities = map[Provider]Capabilities{
+	Polygon: {
+		Timespans:   allTimespans,
+		MaxHistory:  20,
+		Instruments: []string{"stocks", "options"},
+		// TODO: Extend polygon to allow for multiple tickers
+		MultiTicker: false,
+	},
+	TwelveData: {
+		Timespans: map[Timespan][]int{
+			Minute: {1, 5, 15, 30, 45},
+			Hour:   {1, 2, 4, 8},
+			Day:    {1},
+			Week:   {1},
+			Month:  {1},
+		},
+		Instruments: []string{"stocks"},
+		MultiTicker: true,
+	},
+	Csv: {
+		Timespans:   allTimespans,
+		Instruments: []string{"stocks", "options"},
+		MultiTicker: true,
+	},
+}
+
+// Providers returns the providers in the order they are offered to the user.
+func Providers() []Provider {
+	return []Provider{Polygon, TwelveData, Csv}
+}
+
+func GetCapabilities(provider Provider) (Capabilities, error) {
+	capabilities, ok := ProviderCapabilities[provider]
+	if !ok {
+		return Capabilities{}, errors.New(
+			fmt.Sprintf("Data provider '%s' is not supported", provider),
+		)
+	}
+
+	return capabilities, nil
+}
+
+// SupportingProviders returns the providers that offer bars of the quantity
+// of the timespan for the instrument type.
+func SupportingProviders(
+	instrumentType string,
+	timespan Timespan,
+	quantity int,
+) []Provider {
+	var providers []Provider
+	for _, provider := range Providers() {
+		capabilities := ProviderCapabilities[provider]
+		if capabilities.ValidateInstrument(instrumentType) != nil ||
+			capabilities.ValidateTimespan(timespan, quantity) != nil {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+// Validate checks the request against the capabilities of the provider
+// before any data is requested.
+func (p Provider) Validate(apiParams *ApiParams) error {
+	capabilities, err := GetCapabilities(p)
+	if err != nil {
+		return err
+	}
+
+	err = capabilities.ValidateTimespan(apiParams.Timespan, apiParams.Quantity)
+	if err == nil {
+		err = capabilities.ValidateTickers(apiParams.Tickers)
+	}
+	if err == nil && !apiParams.From.IsZero() {
+		err = capabilities.ValidateHistory(apiParams.From)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s: %v", p, err))
+	}
+
+	return nil
+}
+
+func (c Capabilities) ValidateInstrument(instrumentType string) error {
+	for _, instrument := range c.Instruments {
+		if instrument == instrumentType {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf(
+		"Instrument type '%s' is not supported, the supported types are %v",
+		instrumentType,
+		c.Instruments,
+	))
+}
+
+func (c Capabilities) ValidateTimespan(timespan Timespan, quantity int) error {
+	quantities, ok := c.Timespans[timespan]
+	if !ok {
+		return errors.New(fmt.Sprintf(
+			"Timespan '%s' is not supported",
+			timespanUnit(timespan),
+		))
+	}
+
+	if quantities == nil {
+		if quantity < 1 {
+			return errors.New(fmt.Sprintf(
+				"Multiplier '%d' is not supported, it needs to be positive",
+				quantity,
+			))
+		}
+		return nil
+	}
+
+	for _, supportedQuantity := range quantities {
+		if quantity == supportedQuantity {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf(
+		"%d %s bars are not supported, the supported multipliers are %v",
+		quantity,
+		timespanUnit(timespan),
+		quantities,
+	))
+}
+
+// ValidateHistory checks that data from the start date can be requested.
+func (c Capabilities) ValidateHistory(from time.Time) error {
+	if c.MaxHistory == 0 {
+		return nil
+	}
+
+	earliest := time.Now().AddDate(-c.MaxHistory, 0, 0)
+	if from.Before(earliest) {
+		return errors.New(fmt.Sprintf(
+			"Data is only available for the last %d years, the start date needs to be after %s",
+			c.MaxHistory,
+			earliest.Format(time.DateOnly),
+		))
+	}
+
+	return nil
+}
+
+// ValidateTickers checks that providers without multi-ticker support only
+// receive a single ticker.
+func (c Capabilities) ValidateTickers(tickers []string) error {
+	if !c.MultiTicker && len(tickers) > 1 {
+		return errors.New(
+			"Only a single ticker can be imported at once",
+		)
+	}
+
+	return nil
+}
+
+func timespanUnit(timespan Timespan) string {
+	for _, timespanInfo := range Timespans {
+		if timespanInfo.Value == timespan {
+			return timespanInfo.Unit
+		}
+	}
+
+	return fmt.Sprint(int(timespan))
+}
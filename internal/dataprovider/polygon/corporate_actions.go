@@ -0,0 +1,118 @@
+package polygon
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/polygon-io/client-go/rest/models"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/dataprovider"
+)
+
+const SplitsTable = "splits"
+
+const DividendsTable = "dividends"
+
+// ImportCorporateActions upserts the splits and dividends of the tickers
+// between From and To into the splits and dividends tables.
+func ImportCorporateActions(apiParams *dataprovider.ApiParams) error {
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var splitRows, dividendRows [][]any
+	for _, ticker := range apiParams.Tickers {
+		splits := client.ListSplits(
+			context.Background(),
+			models.ListSplitsParams{}.
+				WithTicker(models.EQ, ticker).
+				WithExecutionDate(models.GTE, models.Date(apiParams.From)).
+				WithExecutionDate(models.LTE, models.Date(apiParams.To)),
+		)
+		for splits.Next() {
+			split := splits.Item()
+			splitRows = append(splitRows, []any{
+				ticker,
+				pgDate(time.Time(split.ExecutionDate)),
+				split.SplitFrom,
+				split.SplitTo,
+				string(dataprovider.Polygon),
+			})
+		}
+		if splits.Err() != nil {
+			return splits.Err()
+		}
+
+		dividends := client.ListDividends(
+			context.Background(),
+			models.ListDividendsParams{}.
+				WithTicker(models.EQ, ticker).
+				WithExDividendDate(models.GTE, models.Date(apiParams.From)).
+				WithExDividendDate(models.LTE, models.Date(apiParams.To)),
+		)
+		for dividends.Next() {
+			dividend := dividends.Item()
+			exDividendDate, err := time.Parse(time.DateOnly, dividend.ExDividendDate)
+			if err != nil {
+				return err
+			}
+
+			dividendRows = append(dividendRows, []any{
+				ticker,
+				pgDate(exDividendDate),
+				dividend.CashAmount,
+				dividend.DividendType,
+				dividend.Frequency,
+				pgDate(time.Time(dividend.DeclarationDate)),
+				pgDate(time.Time(dividend.RecordDate)),
+				pgDate(time.Time(dividend.PayDate)),
+				string(dataprovider.Polygon),
+			})
+		}
+		if dividends.Err() != nil {
+			return dividends.Err()
+		}
+	}
+
+	err = db.Upsert(
+		context.Background(),
+		SplitsTable,
+		[]string{"symbol", "execution_date", "split_from", "split_to", "data_provider"},
+		[]string{"symbol", "execution_date"},
+		pgx.CopyFromRows(splitRows),
+	)
+	if err != nil {
+		return err
+	}
+
+	return db.Upsert(
+		context.Background(),
+		DividendsTable,
+		[]string{
+			"symbol",
+			"ex_dividend_date",
+			"cash_amount",
+			"dividend_type",
+			"frequency",
+			"declaration_date",
+			"record_date",
+			"pay_date",
+			"data_provider",
+		},
+		[]string{"symbol", "ex_dividend_date", "dividend_type"},
+		pgx.CopyFromRows(dividendRows),
+	)
+}
+
+// pgDate maps dates that are missing in polygon's response to NULL.
+func pgDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,67 @@
+package polygon
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/polygon-io/client-go/rest/models"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/helper"
+)
+
+// ImportFxRates imports the closing rates of currency pairs in the format
+// "EUR/USD" into the table.
+func ImportFxRates(apiParams *dataprovider.ApiParams) error {
+	timespan, err := mapTimespan(apiParams.Timespan)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	var rows [][]any
+	for _, pair := range apiParams.Tickers {
+		baseCurrency, quoteCurrency, err := dataprovider.ParseCurrencyPair(pair)
+		if err != nil {
+			return err
+		}
+
+		// Polygon expects forex tickers in the format "C:EURUSD"
+		candles := getStockCandles(client, &models.ListAggsParams{
+			Ticker:     "C:" + baseCurrency + quoteCurrency,
+			From:       models.Millis(apiParams.From),
+			To:         models.Millis(apiParams.To),
+			Timespan:   timespan,
+			Multiplier: apiParams.Quantity,
+		})
+		for candles.Next() {
+			item := candles.Item()
+			row := helper.FxRateRow{
+				Time:          time.Time(item.Timestamp),
+				BaseCurrency:  baseCurrency,
+				QuoteCurrency: quoteCurrency,
+				Rate:          helper.FloatToDecimal(item.Close),
+				DataProvider:  string(dataprovider.Polygon),
+			}
+			rows = append(rows, row.Slice())
+		}
+		if candles.Err() != nil {
+			return candles.Err()
+		}
+	}
+
+	return helper.UpsertFxRates(
+		db,
+		apiParams.Table,
+		pgx.CopyFromRows(rows),
+	)
+}
@@ -4,22 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
-	"strconv"
+	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	polygon "github.com/polygon-io/client-go/rest"
 	"github.com/polygon-io/client-go/rest/iter"
 	"github.com/polygon-io/client-go/rest/models"
+	"github.com/premia-ai/cli/internal/calendar"
+	"github.com/premia-ai/cli/internal/credentials"
+	"github.com/premia-ai/cli/internal/database"
 	"github.com/premia-ai/cli/internal/dataprovider"
 	"github.com/premia-ai/cli/internal/helper"
 )
 
 type RowSrcMeta struct {
-	iter   *iter.Iter[models.Agg]
-	ticker string
+	iter     *iter.Iter[models.Agg]
+	ticker   string
+	currency string
 }
 
 type RowSrc struct {
@@ -42,12 +43,12 @@ func (r *RowSrc) Next() bool {
 	item := r.meta.iter.Item()
 	row := helper.MarketDataRow{
 		Time:         time.Time(item.Timestamp),
-		Open:         strconv.FormatFloat(item.Open, 'f', -1, 64),
-		Close:        strconv.FormatFloat(item.Close, 'f', -1, 64),
-		High:         strconv.FormatFloat(item.High, 'f', -1, 64),
-		Low:          strconv.FormatFloat(item.Low, 'f', -1, 64),
-		Volume:       strconv.FormatInt(int64(item.Volume), 10),
-		Currency:     currency,
+		Open:         helper.FloatToDecimal(item.Open),
+		Close:        helper.FloatToDecimal(item.Close),
+		High:         helper.FloatToDecimal(item.High),
+		Low:          helper.FloatToDecimal(item.Low),
+		Volume:       helper.FloatToDecimal(item.Volume),
+		Currency:     r.meta.currency,
 		DataProvider: string(dataprovider.Polygon),
 		Symbol:       r.meta.ticker,
 	}
@@ -64,16 +65,27 @@ func (r *RowSrc) Err() error {
 	return r.err
 }
 
-func NewRowSrc(ticker string, value *iter.Iter[models.Agg]) *RowSrc {
+func NewRowSrc(
+	ticker string,
+	currency string,
+	value *iter.Iter[models.Agg],
+) *RowSrc {
 	return &RowSrc{
 		meta: RowSrcMeta{
-			iter:   value,
-			ticker: ticker,
+			iter:     value,
+			ticker:   ticker,
+			currency: currency,
 		},
 	}
 }
 
-const currency = "USD"
+// defaultCurrency is used when polygon's reference data doesn't contain the
+// currency of a ticker.
+const defaultCurrency = "USD"
+
+// defaultTimezone is used for tickers whose primary exchange has no trading
+// calendar since polygon mainly covers US markets.
+const defaultTimezone = "America/New_York"
 
 // TODO: Extend polygon to allow for multiple tickers
 func ImportMarketData(apiParams *dataprovider.ApiParams) error {
@@ -82,20 +94,27 @@ func ImportMarketData(apiParams *dataprovider.ApiParams) error {
 		return err
 	}
 
-	postgresUrl := os.Getenv("POSTGRES_URL")
-	if postgresUrl == "" {
-		return errors.New("Please set POSTGRES_URL environment variable")
+	err = dataprovider.Polygon.Validate(apiParams)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
 	}
 
-	conn, err := pgx.Connect(context.Background(), postgresUrl)
+	db, err := database.Open()
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"Unable to connect to database: %v\n", err,
-		))
+		return err
 	}
-	defer conn.Close(context.Background())
 
-	candles := getStockCandles(&models.ListAggsParams{
+	currency, symbol, err := getTickerDetails(client, apiParams.Tickers[0])
+	if err != nil {
+		return err
+	}
+
+	candles := getStockCandles(client, &models.ListAggsParams{
 		Ticker:     apiParams.Tickers[0],
 		From:       models.Millis(apiParams.From),
 		To:         models.Millis(apiParams.To),
@@ -103,30 +122,69 @@ func ImportMarketData(apiParams *dataprovider.ApiParams) error {
 		Multiplier: apiParams.Quantity,
 	})
 
-	_, err = conn.CopyFrom(
-		context.Background(),
-		pgx.Identifier{apiParams.Table},
-		helper.MarketDataColumnNames,
-		NewRowSrc(apiParams.Tickers[0], candles),
+	err = helper.UpsertMarketData(
+		db,
+		apiParams.Table,
+		NewRowSrc(apiParams.Tickers[0], currency, candles),
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return helper.UpsertSymbols(
+		db,
+		dataprovider.SymbolsTable,
+		[]helper.SymbolRow{symbol},
+	)
 }
 
-func getStockCandles(apiParams *models.ListAggsParams) *iter.Iter[models.Agg] {
-	polygon_api_key := os.Getenv("POLYGON_API_KEY")
-	if polygon_api_key == "" {
-		// TODO: Set up an alternative to enter the API key in the CLI via
-		// a password input
-		log.Fatal("Please set POLYGON_API_KEY environment variable")
+// getTickerDetails looks up the trading currency and the primary exchange of
+// the ticker in polygon's reference data.
+func getTickerDetails(
+	client *polygon.Client,
+	ticker string,
+) (string, helper.SymbolRow, error) {
+	details, err := client.GetTickerDetails(
+		context.Background(),
+		&models.GetTickerDetailsParams{Ticker: ticker},
+	)
+	if err != nil {
+		return "", helper.SymbolRow{}, err
+	}
+
+	currency := defaultCurrency
+	if details.Results.CurrencyName != "" {
+		currency = strings.ToUpper(details.Results.CurrencyName)
+	}
+
+	symbol := helper.SymbolRow{
+		Symbol:           ticker,
+		Exchange:         details.Results.PrimaryExchange,
+		ExchangeTimezone: defaultTimezone,
+		DataProvider:     string(dataprovider.Polygon),
+	}
+	exchangeCalendar, err := calendar.Get(details.Results.PrimaryExchange)
+	if err == nil {
+		symbol.ExchangeTimezone = exchangeCalendar.Timezone
+	}
+
+	return currency, symbol, nil
+}
+
+func getStockCandles(
+	client *polygon.Client,
+	apiParams *models.ListAggsParams,
+) *iter.Iter[models.Agg] {
+	return client.ListAggs(context.Background(), apiParams)
+}
+
+func newClient() (*polygon.Client, error) {
+	apiKey, err := credentials.Get(credentials.Polygon)
+	if err != nil {
+		return nil, err
 	}
 
-	return polygon.
-		New(polygon_api_key).
-		ListAggs(context.Background(), apiParams)
+	return polygon.New(apiKey), nil
 }
 
 func mapTimespan(timespan dataprovider.Timespan) (models.Timespan, error) {
@@ -0,0 +1,68 @@
+package twelvedata
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/helper"
+)
+
+// ImportFxRates imports the closing rates of currency pairs in the format
+// "EUR/USD" into the table.
+func ImportFxRates(apiParams *dataprovider.ApiParams) error {
+	for _, pair := range apiParams.Tickers {
+		_, _, err := dataprovider.ParseCurrencyPair(pair)
+		if err != nil {
+			return err
+		}
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	instruments, err := getTimeSeries(apiParams)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]any
+	for _, instrument := range instruments {
+		baseCurrency, quoteCurrency, err := dataprovider.ParseCurrencyPair(
+			instrument.MetaData.Symbol,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, timeSeriesValue := range instrument.TimeSeries {
+			t, err := parseDateTime(timeSeriesValue.DateTime, time.UTC)
+			if err != nil {
+				return err
+			}
+
+			rate, err := helper.ParseDecimal(timeSeriesValue.Close)
+			if err != nil {
+				return err
+			}
+
+			row := helper.FxRateRow{
+				Time:          t,
+				BaseCurrency:  baseCurrency,
+				QuoteCurrency: quoteCurrency,
+				Rate:          rate,
+				DataProvider:  string(dataprovider.TwelveData),
+			}
+			rows = append(rows, row.Slice())
+		}
+	}
+
+	return helper.UpsertFxRates(
+		db,
+		apiParams.Table,
+		pgx.CopyFromRows(rows),
+	)
+}
@@ -1,19 +1,18 @@
 package twelvedata
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/premia-ai/cli/internal/credentials"
+	"github.com/premia-ai/cli/internal/database"
 	"github.com/premia-ai/cli/internal/dataprovider"
 	"github.com/premia-ai/cli/internal/helper"
 )
@@ -87,51 +86,113 @@ func NewRowSrc(value []helper.MarketDataRow) *RowSrc {
 }
 
 func ImportMarketData(apiParams *dataprovider.ApiParams) error {
-	postgresUrl := os.Getenv("POSTGRES_URL")
-	if postgresUrl == "" {
-		return errors.New("Please set POSTGRES_URL environment variable")
+	err := dataprovider.TwelveData.Validate(apiParams)
+	if err != nil {
+		return err
 	}
 
-	conn, err := pgx.Connect(context.Background(), postgresUrl)
+	db, err := database.Open()
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"Unable to connect to database: %v\n", err,
-		))
+		return err
 	}
-	defer conn.Close(context.Background())
 
-	candles, err := getAggregates(apiParams)
+	instruments, err := getTimeSeries(apiParams)
 	if err != nil {
 		return err
 	}
 
-	_, err = conn.CopyFrom(
-		context.Background(),
-		pgx.Identifier{apiParams.Table},
-		helper.MarketDataColumnNames,
+	candles, err := getAggregates(instruments)
+	if err != nil {
+		return err
+	}
+
+	err = helper.UpsertMarketData(
+		db,
+		apiParams.Table,
 		NewRowSrc(candles),
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	var symbols []helper.SymbolRow
+	for _, instrument := range instruments {
+		if instrument.MetaData.ExchangeTimezone == "" {
+			continue
+		}
+		symbols = append(symbols, helper.SymbolRow{
+			Symbol:           instrument.MetaData.Symbol,
+			Exchange:         instrument.MetaData.Exchange,
+			ExchangeTimezone: instrument.MetaData.ExchangeTimezone,
+			DataProvider:     string(dataprovider.TwelveData),
+		})
+	}
+
+	return helper.UpsertSymbols(db, dataprovider.SymbolsTable, symbols)
+}
+
+func getAggregates(instruments []ApiResponse) ([]helper.MarketDataRow, error) {
+	var values []helper.MarketDataRow
+	for _, instrument := range instruments {
+		// Daily and bigger bars only contain a date which is the trading date
+		// in the exchange's timezone
+		location := time.UTC
+		if instrument.MetaData.ExchangeTimezone != "" {
+			var err error
+			location, err = time.LoadLocation(instrument.MetaData.ExchangeTimezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, timeSeriesValue := range instrument.TimeSeries {
+			t, err := parseDateTime(timeSeriesValue.DateTime, location)
+			if err != nil {
+				return nil, err
+			}
+
+			row := helper.MarketDataRow{
+				Time:         t,
+				Symbol:       instrument.MetaData.Symbol,
+				Currency:     instrument.MetaData.Currency,
+				DataProvider: string(dataprovider.TwelveData),
+			}
+
+			decimals := []struct {
+				field *pgtype.Numeric
+				value string
+			}{
+				{&row.Open, timeSeriesValue.Open},
+				{&row.Close, timeSeriesValue.Close},
+				{&row.High, timeSeriesValue.High},
+				{&row.Low, timeSeriesValue.Low},
+				{&row.Volume, timeSeriesValue.Volume},
+			}
+			for _, decimal := range decimals {
+				*decimal.field, err = helper.ParseDecimal(decimal.value)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			values = append(values, row)
+		}
+	}
+
+	return values, nil
 }
 
-func getAggregates(apiParams *dataprovider.ApiParams) ([]helper.MarketDataRow, error) {
-	apiKey := os.Getenv("TWELVEDATA_API_KEY")
-	if apiKey == "" {
-		// TODO: Set up an alternative to enter the API key in the CLI via
-		// a password input
-		log.Fatal("Please set TWELVEDATA_API_KEY environment variable")
+func getTimeSeries(apiParams *dataprovider.ApiParams) ([]ApiResponse, error) {
+	apiKey, err := credentials.Get(credentials.TwelveData)
+	if err != nil {
+		return nil, err
 	}
 
 	// Format for interval needs to be: "1min", "1h", "1day", "1week", "1month"
-	timespan, err := mapTimespan(apiParams.Timespan)
+	interval, err := mapInterval(apiParams.Timespan, apiParams.Quantity)
 	if err != nil {
 		return nil, err
 	}
-	interval := fmt.Sprintf("%d%s", apiParams.Quantity, timespan)
 
 	query := url.Values{}
 	query.Set("apikey", apiKey)
@@ -167,36 +228,55 @@ func getAggregates(apiParams *dataprovider.ApiParams) ([]helper.MarketDataRow, e
 		return nil, err
 	}
 
+	// The response is only keyed by symbol when multiple symbols are requested
+	if len(apiParams.Tickers) == 1 {
+		var responseBody ApiResponse
+		err = json.Unmarshal(body, &responseBody)
+		if err != nil {
+			return nil, responseError(err, body)
+		}
+
+		return []ApiResponse{responseBody}, nil
+	}
+
 	var responseBody map[string]ApiResponse
 	err = json.Unmarshal(body, &responseBody)
 	if err != nil {
-		fmt.Fprint(os.Stderr, "body: ", string(body), "\n")
-		return nil, err
+		return nil, responseError(err, body)
 	}
 
-	var values []helper.MarketDataRow
+	var instruments []ApiResponse
 	for _, instrument := range responseBody {
-		for _, timeSeriesValue := range instrument.TimeSeries {
-			t, err := time.Parse(apiTimestamp, timeSeriesValue.DateTime)
-			if err != nil {
-				return nil, err
-			}
+		instruments = append(instruments, instrument)
+	}
 
-			values = append(values, helper.MarketDataRow{
-				Time:         t,
-				Symbol:       instrument.MetaData.Symbol,
-				Open:         timeSeriesValue.Open,
-				Close:        timeSeriesValue.Close,
-				High:         timeSeriesValue.High,
-				Low:          timeSeriesValue.Low,
-				Volume:       timeSeriesValue.Volume,
-				Currency:     instrument.MetaData.Currency,
-				DataProvider: string(dataprovider.TwelveData),
-			})
-		}
+	return instruments, nil
+}
+
+// parseDateTime handles the UTC timestamps of intraday values as well as the
+// dates of daily and bigger values, which start at midnight in the location.
+func parseDateTime(value string, location *time.Location) (time.Time, error) {
+	t, err := time.Parse(apiTimestamp, value)
+	if err == nil {
+		return t, nil
 	}
 
-	return values, nil
+	return time.ParseInLocation(time.DateOnly, value, location)
+}
+
+func mapInterval(timespan dataprovider.Timespan, quantity int) (string, error) {
+	twelvedataTimespan, err := mapTimespan(timespan)
+	if err != nil {
+		return "", err
+	}
+
+	capabilities := dataprovider.ProviderCapabilities[dataprovider.TwelveData]
+	err = capabilities.ValidateTimespan(timespan, quantity)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%s: %v", dataprovider.TwelveData, err))
+	}
+
+	return fmt.Sprintf("%d%s", quantity, twelvedataTimespan), nil
 }
 
 func mapTimespan(timespan dataprovider.Timespan) (Timespan, error) {
@@ -220,3 +300,19 @@ func mapTimespan(timespan dataprovider.Timespan) (Timespan, error) {
 		)
 	}
 }
+
+// maxErrorBodyLength limits how much of an unexpected response is shown
+const maxErrorBodyLength = 200
+
+func responseError(err error, body []byte) error {
+	content := string(body)
+	if len(content) > maxErrorBodyLength {
+		content = content[:maxErrorBodyLength] + "..."
+	}
+
+	return errors.New(fmt.Sprintf(
+		"Twelve Data returned an unexpected response: %v\nResponse: %s",
+		err,
+		content,
+	))
+}
@@ -1,6 +1,11 @@
 package dataprovider
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Provider string
 
@@ -10,6 +15,11 @@ const (
 	Csv        Provider = "csv"
 )
 
+const FxRatesTable = "fx_rates"
+
+// SymbolsTable stores the exchange and its timezone of every imported symbol
+const SymbolsTable = "symbols"
+
 type Timespan int
 
 const (
@@ -28,6 +38,12 @@ type TimespanInfo struct {
 	Value         Timespan
 	Unit          string
 	BiggerUnits   []string
+	// Seconds is the fixed width of the timespan, for calendar based
+	// timespans it is only an approximation that is used to sort them
+	Seconds int
+	// Months is the width of calendar based timespans whose width in
+	// seconds varies
+	Months int
 }
 
 var Timespans = []TimespanInfo{
@@ -35,32 +51,106 @@ var Timespans = []TimespanInfo{
 		OneLetterCode: "s",
 		Value:         Second,
 		Unit:          "second",
-		BiggerUnits:   []string{"minute", "hour", "day", "week"},
+		BiggerUnits:   []string{"minute", "hour", "day", "week", "month", "quarter", "year"},
+		Seconds:       1,
 	},
 	{
 		OneLetterCode: "m",
 		Value:         Minute,
 		Unit:          "minute",
-		BiggerUnits:   []string{"hour", "day", "week"},
+		BiggerUnits:   []string{"hour", "day", "week", "month", "quarter", "year"},
+		Seconds:       60,
 	},
 	{
 		OneLetterCode: "h",
 		Value:         Hour,
 		Unit:          "hour",
-		BiggerUnits:   []string{"day", "week"},
+		BiggerUnits:   []string{"day", "week", "month", "quarter", "year"},
+		Seconds:       60 * 60,
 	},
 	{
 		OneLetterCode: "d",
 		Value:         Day,
 		Unit:          "day",
-		BiggerUnits:   []string{"week"},
+		BiggerUnits:   []string{"week", "month", "quarter", "year"},
+		Seconds:       24 * 60 * 60,
 	},
 	{
 		OneLetterCode: "w",
 		Value:         Week,
 		Unit:          "week",
 		BiggerUnits:   []string{},
+		Seconds:       7 * 24 * 60 * 60,
+	},
+	{
+		OneLetterCode: "M",
+		Value:         Month,
+		Unit:          "month",
+		BiggerUnits:   []string{"quarter", "year"},
+		Seconds:       30 * 24 * 60 * 60,
+		Months:        1,
+	},
+	{
+		OneLetterCode: "q",
+		Value:         Quarter,
+		Unit:          "quarter",
+		BiggerUnits:   []string{"year"},
+		Seconds:       91 * 24 * 60 * 60,
+		Months:        3,
 	},
+	{
+		OneLetterCode: "y",
+		Value:         Year,
+		Unit:          "year",
+		BiggerUnits:   []string{},
+		Seconds:       365 * 24 * 60 * 60,
+		Months:        12,
+	},
+}
+
+const daySeconds = 24 * 60 * 60
+
+// IsCalendarBased reports whether the width of the timespan depends on the
+// calendar, i.e. months, quarters and years.
+func (t TimespanInfo) IsCalendarBased() bool {
+	return t.Months > 0
+}
+
+// Interval returns the timespan as a Postgres interval. Quarters are no valid
+// interval unit, so they are expressed in months.
+func (t TimespanInfo) Interval(quantity int) string {
+	if t.Value == Quarter {
+		return fmt.Sprintf("%d months", quantity*t.Months)
+	}
+
+	return fmt.Sprintf("%d %s", quantity, t.Unit)
+}
+
+// CanAggregate reports whether buckets of the other timespan can be built
+// from whole buckets of this timespan, e.g. hours from 30 minute buckets but
+// not weeks from 2 day buckets. Calendar based timespans can be built from
+// whole days or from whole months.
+func (t TimespanInfo) CanAggregate(
+	quantity int,
+	other TimespanInfo,
+	otherQuantity int,
+) bool {
+	if other.IsCalendarBased() {
+		if t.IsCalendarBased() {
+			months := t.Months * quantity
+			otherMonths := other.Months * otherQuantity
+			return months < otherMonths && otherMonths%months == 0
+		}
+		return daySeconds%(t.Seconds*quantity) == 0
+	}
+	if t.IsCalendarBased() {
+		return false
+	}
+
+	width := t.Seconds * quantity
+	otherWidth := other.Seconds * otherQuantity
+
+	return width < otherWidth && otherWidth%width == 0
 }
 
 type ApiParams struct {
@@ -71,3 +161,29 @@ type ApiParams struct {
 	To       time.Time
 	Table    string
 }
+
+func GetTimespanInfo(unit string) (TimespanInfo, error) {
+	for _, timespan := range Timespans {
+		if timespan.Unit == unit {
+			return timespan, nil
+		}
+	}
+
+	return TimespanInfo{}, errors.New(
+		fmt.Sprintf("Timespan '%s' is not supported", unit),
+	)
+}
+
+// ParseCurrencyPair splits a currency pair in the format "EUR/USD" into its
+// base and quote currency.
+func ParseCurrencyPair(pair string) (string, string, error) {
+	currencies := strings.Split(strings.ToUpper(strings.TrimSpace(pair)), "/")
+	if len(currencies) != 2 || len(currencies[0]) != 3 || len(currencies[1]) != 3 {
+		return "", "", errors.New(fmt.Sprintf(
+			"Currency pair '%s' needs to have the format 'EUR/USD'",
+			pair,
+		))
+	}
+
+	return currencies[0], currencies[1], nil
+}
@@ -0,0 +1,461 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/credentials"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/storage"
+)
+
+type Status string
+
+const (
+	Pass Status = "PASS"
+	// Warn is used for problems that only affect some commands
+	Warn Status = "WARN"
+	Fail Status = "FAIL"
+)
+
+type Check struct {
+	Name    string
+	Status  Status
+	Message string
+	// Fix is empty for checks that passed
+	Fix string
+}
+
+// minPostgresVersion is needed by date_bin of the postgres backend's
+// aggregates.
+const minPostgresVersion = 140000
+
+const permissionCheckTable = "premia_doctor_check"
+
+// Run checks the setup of the active profile. Checks that need the database
+// are skipped if it cannot be reached.
+func Run() []Check {
+	var checks []Check
+
+	configData, configCheck := checkConfig()
+	checks = append(checks, configCheck)
+
+	backend, err := config.Backend()
+	if err != nil {
+		// The config check already reports why the backend is unknown
+		backend = config.BackendTimescale
+	}
+
+	db, connectionCheck := checkConnection(backend)
+	checks = append(checks, connectionCheck)
+	if db != nil {
+		checks = append(checks, checkServerVersion(db))
+		if backend == config.BackendTimescale {
+			checks = append(checks, checkTimescale(db))
+		}
+		checks = append(checks, checkPermissions(db))
+	}
+
+	migrationsCheck, migrationVersions := checkMigrationFiles(configData)
+	checks = append(checks, migrationsCheck)
+	if db != nil && len(migrationVersions) > 0 {
+		checks = append(checks, checkMigrationState(db, migrationVersions))
+	}
+	if db != nil && configData != nil {
+		checks = append(checks, checkTables(configData))
+	}
+
+	checks = append(checks, checkProviderKeys()...)
+
+	return checks
+}
+
+func checkConfig() (*config.ConfigFileData, Check) {
+	check := Check{Name: "Config"}
+
+	// The path cannot be determined if the profile's directory is missing
+	configFilePath, err := config.ConfigFilePath()
+	if err != nil {
+		check.Status = Fail
+		check.Message = err.Error()
+		check.Fix = "Run 'premia init' to set up the database and its config"
+		return nil, check
+	}
+
+	_, err = os.Stat(configFilePath)
+	if os.IsNotExist(err) {
+		check.Status = Fail
+		check.Message = fmt.Sprintf("'%s' doesn't exist", configFilePath)
+		check.Fix = "Run 'premia init' to set up the database and its config"
+		return nil, check
+	}
+	if err != nil {
+		check.Status = Fail
+		check.Message = err.Error()
+		check.Fix = "Check the permissions of the .premia directory"
+		return nil, check
+	}
+
+	configData, err := config.Config()
+	if err != nil {
+		check.Status = Fail
+		check.Message = err.Error()
+		check.Fix = "Fix the config with 'premia config edit'"
+		return nil, check
+	}
+
+	check.Status = Pass
+	check.Message = fmt.Sprintf(
+		"'%s' is valid and uses the %s backend",
+		configFilePath,
+		configData.DatabaseBackend(),
+	)
+	return configData, check
+}
+
+func checkConnection(backend string) (database.DB, Check) {
+	check := Check{Name: "Database connection"}
+
+	db, err := database.Open()
+	if err != nil {
+		check.Status = Fail
+		check.Message = err.Error()
+		check.Fix = "Set POSTGRES_URL or the database settings with 'premia config set database.<key> <value>'"
+		if backend == config.BackendDuckDB {
+			check.Fix = "Make sure that database.path is writable and that DuckDB's icu extension can be installed"
+		}
+		return nil, check
+	}
+
+	check.Status = Pass
+	check.Message = "Connected"
+	if backend == config.BackendDuckDB {
+		duckDBPath, err := config.DuckDBPath()
+		if err == nil {
+			check.Message = fmt.Sprintf("Opened '%s'", duckDBPath)
+		}
+	}
+	return db, check
+}
+
+func checkServerVersion(db database.DB) Check {
+	check := Check{Name: "Server version"}
+
+	if db.Backend() == config.BackendDuckDB {
+		var version string
+		err := db.QueryRow(context.Background(), "SELECT version();").Scan(&version)
+		if err != nil {
+			return failed(check, err)
+		}
+
+		check.Status = Pass
+		check.Message = "DuckDB " + version
+		return check
+	}
+
+	var version string
+	var versionNum int
+	err := db.QueryRow(
+		context.Background(),
+		"SELECT current_setting('server_version'), current_setting('server_version_num')::int;",
+	).Scan(&version, &versionNum)
+	if err != nil {
+		return failed(check, err)
+	}
+
+	if db.Backend() == config.BackendPostgres && versionNum < minPostgresVersion {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"PostgreSQL %s is too old for the postgres backend",
+			version,
+		)
+		check.Fix = "Upgrade to PostgreSQL 14 or newer"
+		return check
+	}
+
+	check.Status = Pass
+	check.Message = "PostgreSQL " + version
+	return check
+}
+
+func checkTimescale(db database.DB) Check {
+	check := Check{Name: "TimescaleDB"}
+
+	var defaultVersion string
+	var installedVersion *string
+	err := db.QueryRow(
+		context.Background(),
+		"SELECT default_version, installed_version FROM pg_available_extensions WHERE name = 'timescaledb';",
+	).Scan(&defaultVersion, &installedVersion)
+	if err != nil {
+		check.Status = Fail
+		check.Message = "The timescaledb extension is not available on the server"
+		check.Fix = "Install TimescaleDB on the server or use 'premia init --backend postgres'"
+		return check
+	}
+
+	if installedVersion != nil {
+		check.Status = Pass
+		check.Message = fmt.Sprintf("Version %s is installed", *installedVersion)
+		return check
+	}
+
+	// Creating extensions needs the CREATE privilege on the database
+	var canCreate bool
+	err = db.QueryRow(
+		context.Background(),
+		"SELECT has_database_privilege(current_database(), 'CREATE');",
+	).Scan(&canCreate)
+	if err != nil {
+		return failed(check, err)
+	}
+	if !canCreate {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"Version %s is available, but the user cannot create extensions in the database",
+			defaultVersion,
+		)
+		check.Fix = "Run 'CREATE EXTENSION timescaledb;' as a superuser or grant the user CREATE on the database"
+		return check
+	}
+
+	check.Status = Pass
+	check.Message = fmt.Sprintf(
+		"Version %s is available and is installed by 'premia init'",
+		defaultVersion,
+	)
+	return check
+}
+
+// checkPermissions creates and drops a table since privileges can be granted
+// in too many ways to check them individually.
+func checkPermissions(db database.DB) Check {
+	check := Check{Name: "Create tables"}
+
+	// The table is created in a transaction that is rolled back so that the
+	// check never leaves it behind
+	err := db.ExecRolledBack(
+		context.Background(),
+		fmt.Sprintf("CREATE TABLE %s (id INT);", permissionCheckTable),
+		fmt.Sprintf("DROP TABLE %s;", permissionCheckTable),
+	)
+	if err != nil {
+		check.Status = Fail
+		check.Message = fmt.Sprintf("The user cannot create tables: %v", err)
+		check.Fix = "Grant the user CREATE on the schema, e.g. 'GRANT CREATE ON SCHEMA public TO <user>;'"
+		if db.Backend() == config.BackendDuckDB {
+			check.Fix = "Make sure that database.path is writable and not opened by another process"
+		}
+		return check
+	}
+
+	check.Status = Pass
+	check.Message = "The user can create tables"
+	return check
+}
+
+// checkMigrationFiles checks that every migration has an up and a down file
+// and returns the versions of the migrations.
+func checkMigrationFiles(configData *config.ConfigFileData) (Check, []uint) {
+	check := Check{Name: "Migration files"}
+
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		check.Status = Fail
+		check.Message = err.Error()
+		check.Fix = "Run 'premia init' to create the migrations"
+		if configData != nil {
+			check.Fix = "Restore the migrations directory, e.g. from version control"
+		}
+		return check, nil
+	}
+
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return failed(check, err), nil
+	}
+
+	directions := make(map[uint][]source.Direction)
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+		directions[migration.Version] = append(
+			directions[migration.Version],
+			migration.Direction,
+		)
+	}
+
+	var versions []uint
+	var incomplete []string
+	for version, versionDirections := range directions {
+		versions = append(versions, version)
+		if len(versionDirections) != 2 {
+			incomplete = append(incomplete, fmt.Sprintf("%d", version))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	sort.Strings(incomplete)
+
+	if len(versions) == 0 {
+		check.Status = Fail
+		check.Message = fmt.Sprintf("'%s' contains no migrations", migrationsDir)
+		check.Fix = "Run 'premia init' to create the migrations"
+		return check, nil
+	}
+	if len(incomplete) > 0 {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"Migration %s lacks its up or down file",
+			incomplete[0],
+		)
+		if len(incomplete) > 1 {
+			check.Message = fmt.Sprintf(
+				"Migrations %s lack their up or down file",
+				strings.Join(incomplete, ", "),
+			)
+		}
+		check.Fix = fmt.Sprintf("Restore the missing files in '%s'", migrationsDir)
+		return check, versions
+	}
+
+	check.Status = Pass
+	check.Message = fmt.Sprintf("%d migrations in '%s'", len(versions), migrationsDir)
+	return check, versions
+}
+
+// checkMigrationState compares the version in schema_migrations with the
+// migration files.
+func checkMigrationState(db database.DB, versions []uint) Check {
+	check := Check{Name: "Applied migrations"}
+
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return failed(check, err)
+	}
+
+	// schema_migrations is read directly since golang-migrate would create it
+	version, dirty, applied, err := database.MigrationVersion(db)
+	if err != nil {
+		return failed(check, err)
+	}
+	if !applied {
+		check.Status = Fail
+		check.Message = "No migration has been applied to the database"
+		check.Fix = "Run 'premia init' to apply the migrations"
+		return check
+	}
+
+	if dirty {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"Migration %d failed and left schema_migrations in a dirty state",
+			version,
+		)
+		check.Fix = fmt.Sprintf(
+			"Undo the partial changes of migration %d in '%s' by hand, then run 'UPDATE schema_migrations SET version = %d, dirty = false;'",
+			version,
+			migrationsDir,
+			previousVersion(versions, version),
+		)
+		return check
+	}
+
+	latestVersion := versions[len(versions)-1]
+	if version < latestVersion {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"The database is at migration %d, but the latest migration is %d",
+			version,
+			latestVersion,
+		)
+		check.Fix = "Apply the pending migrations with 'premia init' or remove the files that were added by hand"
+		return check
+	}
+	if version > latestVersion {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"The database is at migration %d, which doesn't exist in '%s'",
+			version,
+			migrationsDir,
+		)
+		check.Fix = "Restore the missing migration files or use the profile that created the database"
+		return check
+	}
+
+	check.Status = Pass
+	check.Message = fmt.Sprintf("The database is at the latest migration %d", version)
+	return check
+}
+
+// checkTables checks that the tables and views of the config exist.
+func checkTables(configData *config.ConfigFileData) Check {
+	check := Check{Name: "Configured tables"}
+
+	tables := storage.ConfiguredTables(configData)
+	missingTables, err := storage.MissingTables(tables)
+	if err != nil {
+		return failed(check, err)
+	}
+
+	if len(missingTables) > 0 {
+		check.Status = Fail
+		check.Message = fmt.Sprintf(
+			"%s referenced by the config don't exist in the database",
+			strings.Join(missingTables, ", "),
+		)
+		check.Fix = "Apply the pending migrations or remove the tables from the config with 'premia config edit'"
+		return check
+	}
+
+	check.Status = Pass
+	check.Message = fmt.Sprintf("All %d tables and views exist", len(tables))
+	return check
+}
+
+func checkProviderKeys() []Check {
+	var checks []Check
+	for _, name := range []string{credentials.Polygon, credentials.TwelveData} {
+		check := Check{Name: name + " API key"}
+
+		_, err := credentials.Get(name)
+		if err != nil {
+			// Most setups only use one of the data providers
+			check.Status = Warn
+			check.Message = "No API key is stored"
+			check.Fix = fmt.Sprintf("Run 'premia auth set %s' to import data from %s", name, name)
+		} else {
+			check.Status = Pass
+			check.Message = "An API key is available"
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func failed(check Check, err error) Check {
+	check.Status = Fail
+	check.Message = err.Error()
+	check.Fix = "Resolve the error and run 'premia doctor' again"
+	return check
+}
+
+func previousVersion(versions []uint, version uint) int {
+	previous := -1
+	for _, v := range versions {
+		if v < version {
+			previous = int(v)
+		}
+	}
+
+	return previous
+}
@@ -0,0 +1,882 @@
+package drift
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/storage"
+)
+
+// ChecksumsTable stores the checksums of the migration files at the time
+// they were applied.
+const ChecksumsTable = "schema_migration_checksums"
+
+// DefinitionsTable stores the checksums of the definitions of the tables,
+// views and functions at the time the migrations that created or changed them
+// were applied.
+const DefinitionsTable = "schema_object_definitions"
+
+type Problem string
+
+const (
+	Missing    Problem = "missing"
+	Modified   Problem = "modified"
+	Unexpected Problem = "unexpected"
+	// Dirty is reported for a migration that failed midway
+	Dirty Problem = "dirty"
+)
+
+const (
+	KindMigration = "migration"
+	KindTable     = "table"
+	KindView      = "view"
+	KindFunction  = "function"
+)
+
+type Drift struct {
+	Problem Problem `json:"problem"`
+	Kind    string  `json:"kind"`
+	Object  string  `json:"object"`
+	Detail  string  `json:"detail"`
+}
+
+type migrationFile struct {
+	name    string
+	version uint
+	up      bool
+}
+
+type schemaObject struct {
+	kind string
+	// version is the migration that created the object
+	version uint
+}
+
+// statementPattern finds the tables, views and functions that migrations
+// create and drop, also by quoted and schema qualified names. Partitions are
+// matched too so they can be skipped.
+var statementPattern = regexp.MustCompile(
+	`(?is)\b(CREATE|DROP)\s+(?:OR\s+REPLACE\s+)?(MATERIALIZED\s+VIEW|VIEW|TABLE|FUNCTION|MACRO)\s+(?:IF\s+(?:NOT\s+)?EXISTS\s+)?(?:"?[a-z_][a-z0-9_]*"?\.)?"?([a-z_][a-z0-9_]*)"?(\s+PARTITION\s+OF)?`,
+)
+
+var commentPattern = regexp.MustCompile(`--[^\n]*`)
+
+// alterPattern finds the tables and views whose definitions migrations change
+// without recreating them.
+var alterPattern = regexp.MustCompile(
+	`(?is)\bALTER\s+(?:MATERIALIZED\s+VIEW|VIEW|TABLE)\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(?:"?[a-z_][a-z0-9_]*"?\.)?"?([a-z_][a-z0-9_]*)"?`,
+)
+
+// RecordChecksums stores the checksums of the migration files up to version
+// and of the definitions of the objects they create. Checksums that were
+// recorded before are only replaced if overwrite is set, definitions are also
+// replaced for objects that the migrations after previousVersion change.
+func RecordChecksums(
+	migrationsDir string,
+	previousVersion uint,
+	version uint,
+	overwrite bool,
+) error {
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	err = db.Exec(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			file TEXT PRIMARY KEY,
+			version BIGINT NOT NULL,
+			checksum TEXT NOT NULL
+		);`,
+		ChecksumsTable,
+	))
+	if err != nil {
+		return err
+	}
+
+	files, err := migrationFiles(migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	conflictAction := "DO NOTHING"
+	if overwrite {
+		conflictAction = "DO UPDATE SET version = EXCLUDED.version, checksum = EXCLUDED.checksum"
+	}
+	for _, file := range files {
+		if file.version > version {
+			continue
+		}
+
+		checksum, err := fileChecksum(path.Join(migrationsDir, file.name))
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec(
+			ctx,
+			fmt.Sprintf(
+				"INSERT INTO %s (file, version, checksum) VALUES ($1, $2, $3) ON CONFLICT (file) %s;",
+				ChecksumsTable,
+				conflictAction,
+			),
+			file.name,
+			int64(file.version),
+			checksum,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Checksums of rolled back migrations would be reported as deleted files
+	err = db.Exec(
+		ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE version > $1;", ChecksumsTable),
+		int64(version),
+	)
+	if err != nil {
+		return err
+	}
+
+	return recordDefinitions(db, migrationsDir, files, previousVersion, version, overwrite)
+}
+
+func recordDefinitions(
+	db database.DB,
+	migrationsDir string,
+	files []migrationFile,
+	previousVersion uint,
+	version uint,
+	overwrite bool,
+) error {
+	ctx := context.Background()
+	err := db.Exec(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			name TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			checksum TEXT NOT NULL
+		);`,
+		DefinitionsTable,
+	))
+	if err != nil {
+		return err
+	}
+
+	objects, err := expectedObjects(migrationsDir, files, version)
+	if err != nil {
+		return err
+	}
+
+	changed, err := changedObjects(migrationsDir, files, previousVersion, version)
+	if err != nil {
+		return err
+	}
+
+	for name, object := range objects {
+		checksum, err := definitionChecksum(db, name, object.kind)
+		if err != nil {
+			return err
+		}
+		// Missing objects are reported by detectObjectDrift
+		if checksum == "" {
+			continue
+		}
+
+		conflictAction := "DO NOTHING"
+		if overwrite || changed[name] {
+			conflictAction = "DO UPDATE SET kind = EXCLUDED.kind, checksum = EXCLUDED.checksum"
+		}
+		err = db.Exec(
+			ctx,
+			fmt.Sprintf(
+				"INSERT INTO %s (name, kind, checksum) VALUES ($1, $2, $3) ON CONFLICT (name) %s;",
+				DefinitionsTable,
+				conflictAction,
+			),
+			name,
+			object.kind,
+			checksum,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	recorded, err := recordedDefinitions(db)
+	if err != nil {
+		return err
+	}
+	for name := range recorded {
+		if _, ok := objects[name]; ok {
+			continue
+		}
+
+		err = db.Exec(
+			ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE name = $1;", DefinitionsTable),
+			name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changedObjects returns the objects that the up migrations after
+// previousVersion create, drop or alter.
+func changedObjects(
+	migrationsDir string,
+	files []migrationFile,
+	previousVersion uint,
+	version uint,
+) (map[string]bool, error) {
+	changed := make(map[string]bool)
+	for _, file := range files {
+		if !file.up || file.version <= previousVersion || file.version > version {
+			continue
+		}
+
+		content, err := os.ReadFile(path.Join(migrationsDir, file.name))
+		if err != nil {
+			return nil, err
+		}
+
+		sql := commentPattern.ReplaceAllString(string(content), "")
+		for _, match := range statementPattern.FindAllStringSubmatch(sql, -1) {
+			changed[strings.ToLower(match[3])] = true
+		}
+		for _, match := range alterPattern.FindAllStringSubmatch(sql, -1) {
+			changed[strings.ToLower(match[1])] = true
+		}
+	}
+
+	return changed, nil
+}
+
+func recordedDefinitions(db database.DB) (map[string]string, error) {
+	checksums := make(map[string]string)
+	exists, err := db.TableExists(context.Background(), DefinitionsTable)
+	if err != nil || !exists {
+		return checksums, err
+	}
+
+	rows, err := db.Query(
+		context.Background(),
+		fmt.Sprintf("SELECT name, checksum FROM %s;", DefinitionsTable),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, checksum string
+		err = rows.Scan(&name, &checksum)
+		if err != nil {
+			return nil, err
+		}
+		checksums[name] = checksum
+	}
+
+	return checksums, rows.Err()
+}
+
+// definitionChecksum returns the checksum of the object's definition in the
+// database: the query of views, the columns of tables and the source of
+// functions. It's empty if the object doesn't exist.
+func definitionChecksum(db database.DB, name, kind string) (string, error) {
+	var query string
+	switch {
+	case db.Backend() == config.BackendDuckDB && kind == KindFunction:
+		query = `SELECT string_agg(macro_definition, chr(10) ORDER BY macro_definition)
+			FROM duckdb_functions()
+			WHERE NOT internal AND function_type IN ('macro', 'table_macro')
+				AND function_name = $1;`
+	case db.Backend() == config.BackendDuckDB:
+		query = `SELECT COALESCE(
+			(
+				SELECT max(sql) FROM duckdb_views()
+				WHERE NOT internal AND schema_name = 'main' AND view_name = $1
+			),
+			(
+				SELECT string_agg(
+					column_name || ' ' || data_type || CASE WHEN is_nullable THEN '' ELSE ' NOT NULL' END,
+					', ' ORDER BY column_index
+				)
+				FROM duckdb_columns()
+				WHERE schema_name = 'main' AND table_name = $1
+			)
+		);`
+	case kind == KindFunction:
+		query = `SELECT string_agg(pg_get_functiondef(p.oid), chr(10) ORDER BY p.oid::regprocedure::text)
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = current_schema() AND p.proname = $1
+				AND p.prokind IN ('f', 'p');`
+	default:
+		query = `SELECT (
+			SELECT CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid, true) ELSE (
+				SELECT string_agg(
+					a.attname || ' ' || format_type(a.atttypid, a.atttypmod) || CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+					', ' ORDER BY a.attnum
+				)
+				FROM pg_attribute a
+				WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+			) END
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = current_schema() AND c.relname = $1
+		);`
+	}
+
+	var definition *string
+	err := db.QueryRow(context.Background(), query, name).Scan(&definition)
+	if err != nil {
+		return "", err
+	}
+	if definition == nil {
+		return "", nil
+	}
+
+	checksum := sha256.Sum256([]byte(*definition))
+	return hex.EncodeToString(checksum[:]), nil
+}
+
+// Detect compares the migration files with the checksums that were recorded
+// when they were applied and the objects that the applied migrations and the
+// config define with the tables, views and functions in the database.
+func Detect() ([]Drift, error) {
+	configData, err := config.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return nil, err
+	}
+
+	version, dirty, err := appliedVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := migrationFiles(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	if dirty {
+		drifts = append(drifts, Drift{
+			Problem: Dirty,
+			Kind:    KindMigration,
+			Object:  upFileName(files, version),
+			Detail:  "failed and left the database in a dirty state, run 'premia doctor' for details",
+		})
+	}
+
+	fileDrifts, err := detectFileDrift(migrationsDir, files, version)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, fileDrifts...)
+
+	expected, err := expectedObjects(migrationsDir, files, version)
+	if err != nil {
+		return nil, err
+	}
+
+	objectDrifts, err := detectObjectDrift(configData, expected)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, objectDrifts...)
+
+	return drifts, nil
+}
+
+// Accept records the checksums of the current migration files, e.g. after
+// an intended change to an applied migration.
+func Accept() error {
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := appliedVersion()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return errors.New(fmt.Sprintf(
+			"Migration %d failed and left the database in a dirty state, please fix it by hand first.\nRun 'premia doctor' for details.",
+			version,
+		))
+	}
+
+	return RecordChecksums(migrationsDir, version, version, true)
+}
+
+// appliedVersion reads schema_migrations directly, so that detecting drift
+// never creates it.
+func appliedVersion() (uint, bool, error) {
+	db, err := database.Open()
+	if err != nil {
+		return 0, false, err
+	}
+
+	version, dirty, _, err := database.MigrationVersion(db)
+	return version, dirty, err
+}
+
+// upFileName returns the up file of the version or the version itself if the
+// file is missing.
+func upFileName(files []migrationFile, version uint) string {
+	for _, file := range files {
+		if file.up && file.version == version {
+			return file.name
+		}
+	}
+	return fmt.Sprintf("%d", version)
+}
+
+func detectFileDrift(
+	migrationsDir string,
+	files []migrationFile,
+	version uint,
+) ([]Drift, error) {
+	recorded, err := recordedChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	existing := make(map[string]bool)
+	for _, file := range files {
+		existing[file.name] = true
+
+		if file.version > version {
+			drifts = append(drifts, Drift{
+				Problem: Unexpected,
+				Kind:    KindMigration,
+				Object:  file.name,
+				Detail:  fmt.Sprintf("not applied, the database is at migration %d", version),
+			})
+			continue
+		}
+
+		recordedChecksum, ok := recorded[file.name]
+		if !ok {
+			// Databases that were set up before checksums were recorded
+			continue
+		}
+
+		checksum, err := fileChecksum(path.Join(migrationsDir, file.name))
+		if err != nil {
+			return nil, err
+		}
+		if checksum != recordedChecksum {
+			drifts = append(drifts, Drift{
+				Problem: Modified,
+				Kind:    KindMigration,
+				Object:  file.name,
+				Detail:  "changed after it was applied",
+			})
+		}
+	}
+
+	var deleted []string
+	for file := range recorded {
+		if !existing[file] {
+			deleted = append(deleted, file)
+		}
+	}
+	sort.Strings(deleted)
+	for _, file := range deleted {
+		drifts = append(drifts, Drift{
+			Problem: Missing,
+			Kind:    KindMigration,
+			Object:  file,
+			Detail:  fmt.Sprintf("applied, but deleted from '%s'", migrationsDir),
+		})
+	}
+
+	return drifts, nil
+}
+
+func recordedChecksums() (map[string]string, error) {
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string)
+	exists, err := db.TableExists(context.Background(), ChecksumsTable)
+	if err != nil || !exists {
+		return checksums, err
+	}
+
+	rows, err := db.Query(
+		context.Background(),
+		fmt.Sprintf("SELECT file, checksum FROM %s;", ChecksumsTable),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var file, checksum string
+		err = rows.Scan(&file, &checksum)
+		if err != nil {
+			return nil, err
+		}
+		checksums[file] = checksum
+	}
+
+	return checksums, rows.Err()
+}
+
+type Object struct {
+	Name string
+	// Kind is one of KindTable, KindView and KindFunction
+	Kind string
+}
+
+// expectedObjects replays the create and drop statements of the applied up
+// migrations.
+func expectedObjects(
+	migrationsDir string,
+	files []migrationFile,
+	version uint,
+) (map[string]schemaObject, error) {
+	objects := make(map[string]schemaObject)
+	for _, file := range files {
+		if !file.up || file.version > version {
+			continue
+		}
+
+		content, err := os.ReadFile(path.Join(migrationsDir, file.name))
+		if err != nil {
+			// Deleted files are reported by detectFileDrift
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		sql := commentPattern.ReplaceAllString(string(content), "")
+		for _, match := range statementPattern.FindAllStringSubmatch(sql, -1) {
+			if match[4] != "" {
+				continue
+			}
+
+			name := strings.ToLower(match[3])
+			if strings.EqualFold(match[1], "DROP") {
+				delete(objects, name)
+				continue
+			}
+
+			objects[name] = schemaObject{
+				kind:    statementKind(match[2]),
+				version: file.version,
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// DroppedObjects returns the tables, views and functions that the SQL drops
+// in the order of its statements.
+func DroppedObjects(sql string) []Object {
+	var objects []Object
+	sql = commentPattern.ReplaceAllString(sql, "")
+	for _, match := range statementPattern.FindAllStringSubmatch(sql, -1) {
+		if match[4] != "" || !strings.EqualFold(match[1], "DROP") {
+			continue
+		}
+
+		objects = append(objects, Object{
+			Name: strings.ToLower(match[3]),
+			Kind: statementKind(match[2]),
+		})
+	}
+
+	return objects
+}
+
+// statementKind returns the kind of the object type of a statement, e.g.
+// KindView for MATERIALIZED VIEW.
+func statementKind(objectType string) string {
+	switch strings.ToUpper(strings.Join(strings.Fields(objectType), " ")) {
+	case "VIEW", "MATERIALIZED VIEW":
+		return KindView
+	case "FUNCTION", "MACRO":
+		return KindFunction
+	}
+	return KindTable
+}
+
+func detectObjectDrift(
+	configData *config.ConfigFileData,
+	expected map[string]schemaObject,
+) ([]Drift, error) {
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := catalogRelations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, table := range storage.ConfiguredTables(configData) {
+		if _, ok := expected[table]; ok {
+			continue
+		}
+		if _, ok := catalog[table]; ok {
+			continue
+		}
+
+		drifts = append(drifts, Drift{
+			Problem: Missing,
+			Kind:    KindTable,
+			Object:  table,
+			Detail:  "referenced by the config, but not created by any migration",
+		})
+	}
+
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		object := expected[name]
+
+		if object.kind == KindFunction {
+			exists, err := FunctionExists(db, name)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				drifts = append(drifts, Drift{
+					Problem: Missing,
+					Kind:    KindFunction,
+					Object:  name,
+					Detail:  fmt.Sprintf("created by migration %d, but doesn't exist in the database", object.version),
+				})
+			}
+			continue
+		}
+
+		kind, ok := catalog[name]
+		if !ok {
+			drifts = append(drifts, Drift{
+				Problem: Missing,
+				Kind:    object.kind,
+				Object:  name,
+				Detail:  fmt.Sprintf("created by migration %d, but doesn't exist in the database", object.version),
+			})
+		} else if kind != object.kind {
+			drifts = append(drifts, Drift{
+				Problem: Modified,
+				Kind:    kind,
+				Object:  name,
+				Detail:  fmt.Sprintf("created as a %s by migration %d", object.kind, object.version),
+			})
+		}
+	}
+
+	definitionDrifts, err := detectDefinitionDrift(db, expected, catalog)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, definitionDrifts...)
+
+	var catalogNames []string
+	for name := range catalog {
+		catalogNames = append(catalogNames, name)
+	}
+	sort.Strings(catalogNames)
+	for _, name := range catalogNames {
+		if _, ok := expected[name]; ok {
+			continue
+		}
+
+		drifts = append(drifts, Drift{
+			Problem: Unexpected,
+			Kind:    catalog[name],
+			Object:  name,
+			Detail:  "not created by any migration",
+		})
+	}
+
+	return drifts, nil
+}
+
+// detectDefinitionDrift compares the definitions of the objects with the
+// ones recorded when the migrations were applied, e.g. to find views that
+// were replaced by hand.
+func detectDefinitionDrift(
+	db database.DB,
+	expected map[string]schemaObject,
+	catalog map[string]string,
+) ([]Drift, error) {
+	recorded, err := recordedDefinitions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drifts []Drift
+	for _, name := range names {
+		object := expected[name]
+		recordedChecksum, ok := recorded[name]
+		// Databases that were set up before definitions were recorded and
+		// objects whose kind changed, which detectObjectDrift reports
+		if !ok || (object.kind != KindFunction && catalog[name] != object.kind) {
+			continue
+		}
+
+		checksum, err := definitionChecksum(db, name, object.kind)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != "" && checksum != recordedChecksum {
+			drifts = append(drifts, Drift{
+				Problem: Modified,
+				Kind:    object.kind,
+				Object:  name,
+				Detail:  "its definition changed after the migrations were applied",
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// catalogRelations returns the tables and views of the database's default
+// schema without partitions and premia's bookkeeping tables.
+func catalogRelations(db database.DB) (map[string]string, error) {
+	query := `SELECT c.relname, CASE WHEN c.relkind IN ('v', 'm') THEN 'view' ELSE 'table' END
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema()
+			AND c.relkind IN ('r', 'p', 'v', 'm')
+			AND NOT c.relispartition;`
+	if db.Backend() == config.BackendDuckDB {
+		query = `SELECT table_name, 'table' FROM duckdb_tables()
+			WHERE NOT internal AND NOT temporary AND schema_name = 'main'
+			UNION ALL
+			SELECT view_name, 'view' FROM duckdb_views()
+			WHERE NOT internal AND NOT temporary AND schema_name = 'main';`
+	}
+
+	rows, err := db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := make(map[string]string)
+	for rows.Next() {
+		var name, kind string
+		err = rows.Scan(&name, &kind)
+		if err != nil {
+			return nil, err
+		}
+		if name == "schema_migrations" ||
+			name == ChecksumsTable ||
+			name == DefinitionsTable {
+			continue
+		}
+		relations[name] = kind
+	}
+
+	return relations, rows.Err()
+}
+
+// FunctionExists only looks for the function's name since TimescaleDB adds
+// its own functions to the default schema, so unexpected functions aren't
+// reported.
+func FunctionExists(db database.DB, name string) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = current_schema() AND p.proname = $1
+	);`
+	if db.Backend() == config.BackendDuckDB {
+		query = `SELECT EXISTS (
+			SELECT 1 FROM duckdb_functions()
+			WHERE NOT internal AND function_type = 'macro' AND function_name = $1
+		);`
+	}
+
+	var exists bool
+	err := db.QueryRow(context.Background(), query, name).Scan(&exists)
+	return exists, err
+}
+
+// migrationFiles returns the up and down files of the migrations directory
+// ordered by version.
+func migrationFiles(migrationsDir string) ([]migrationFile, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []migrationFile
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		files = append(files, migrationFile{
+			name:    entry.Name(),
+			version: migration.Version,
+			up:      migration.Direction == source.Up,
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].version < files[j].version
+	})
+
+	return files, nil
+}
+
+func fileChecksum(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	checksum := sha256.Sum256(content)
+	return hex.EncodeToString(checksum[:]), nil
+}
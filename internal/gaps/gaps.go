@@ -0,0 +1,457 @@
+package gaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/premia-ai/cli/internal/calendar"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/dataprovider"
+	"github.com/premia-ai/cli/internal/dataprovider/polygon"
+	"github.com/premia-ai/cli/internal/dataprovider/twelvedata"
+)
+
+type Params struct {
+	Table    string
+	Timespan dataprovider.TimespanInfo
+	// Quantity is the multiplier of the timespan, e.g. 5 for 5 minute bars
+	Quantity int
+	Calendar *calendar.Calendar
+	Symbols  []string
+	// From and To are optional, by default the range between the first and
+	// the last bar of every symbol is checked.
+	From time.Time
+	To   time.Time
+}
+
+// Gap is a run of consecutive missing bars. From is the start of the first
+// missing bar and To is the end of the last missing bar.
+type Gap struct {
+	Symbol       string
+	From         time.Time
+	To           time.Time
+	Bars         int
+	DataProvider string
+}
+
+type symbolBars struct {
+	first        time.Time
+	last         time.Time
+	dataProvider string
+	bars         map[int64]bool
+}
+
+type slot struct {
+	key   int64
+	start time.Time
+	end   time.Time
+}
+
+func Detect(params *Params) ([]Gap, error) {
+	switch params.Timespan.Value {
+	case dataprovider.Second,
+		dataprovider.Minute,
+		dataprovider.Hour,
+		dataprovider.Day,
+		dataprovider.Week,
+		dataprovider.Month,
+		dataprovider.Quarter,
+		dataprovider.Year:
+	default:
+		return nil, errors.New(fmt.Sprintf(
+			"Gap detection is not supported for timespan '%s'",
+			params.Timespan.Unit,
+		))
+	}
+	if params.Quantity > 1 && params.Timespan.Seconds >= 24*60*60 {
+		return nil, errors.New(fmt.Sprintf(
+			"Gap detection is not supported for %d %s bars",
+			params.Quantity,
+			params.Timespan.Unit,
+		))
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	symbols, err := queryBars(db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addMissingSymbols(params, symbols, func(symbol string) (string, error) {
+		return symbolDataProvider(db, params.Table, symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return detectGaps(params, symbols), nil
+}
+
+// addMissingSymbols adds the requested symbols that have no bars in the
+// range, so that an outage of a whole symbol is reported as one gap.
+func addMissingSymbols(
+	params *Params,
+	symbols map[string]*symbolBars,
+	dataProvider func(symbol string) (string, error),
+) error {
+	for _, symbol := range params.Symbols {
+		if _, ok := symbols[symbol]; ok {
+			continue
+		}
+
+		if params.From.IsZero() {
+			return errors.New(fmt.Sprintf(
+				"'%s' has no bars in '%s', please pass --from to report the missing range",
+				symbol,
+				params.Table,
+			))
+		}
+
+		provider, err := dataProvider(symbol)
+		if err != nil {
+			return err
+		}
+		if provider == "" {
+			return errors.New(fmt.Sprintf(
+				"'%s' has no bars in '%s' and is missing in the %s table, so its data provider is unknown",
+				symbol,
+				params.Table,
+				dataprovider.SymbolsTable,
+			))
+		}
+
+		to := params.To
+		if to.IsZero() {
+			to = time.Now()
+		}
+		symbols[symbol] = &symbolBars{
+			first:        params.From,
+			last:         to,
+			dataProvider: provider,
+			bars:         make(map[int64]bool),
+		}
+	}
+
+	return nil
+}
+
+// symbolDataProvider returns the data provider of the symbol's latest bar
+// and falls back to the one in the symbols table. It's empty if neither is
+// known.
+func symbolDataProvider(db database.DB, table, symbol string) (string, error) {
+	var provider string
+	err := db.QueryRow(
+		context.Background(),
+		fmt.Sprintf(
+			`SELECT COALESCE(
+				(SELECT data_provider FROM %s WHERE symbol = $1 ORDER BY time DESC LIMIT 1),
+				(SELECT data_provider FROM %s WHERE symbol = $1),
+				''
+			)`,
+			pgx.Identifier{table}.Sanitize(),
+			pgx.Identifier{dataprovider.SymbolsTable}.Sanitize(),
+		),
+		symbol,
+	).Scan(&provider)
+	return provider, err
+}
+
+// detectGaps compares the bars of every symbol with the bars that the
+// calendar expects between From and To or the symbol's first and last bar.
+func detectGaps(params *Params, symbols map[string]*symbolBars) []Gap {
+	var gaps []Gap
+	for _, symbol := range sortedKeys(symbols) {
+		bars := symbols[symbol]
+
+		from, to := bars.first, bars.last
+		if !params.From.IsZero() {
+			from = params.From
+		}
+		if !params.To.IsZero() {
+			to = params.To
+		}
+
+		var gap *Gap
+		for _, s := range expectedSlots(params, from, to) {
+			if bars.bars[s.key] {
+				if gap != nil {
+					gaps = append(gaps, *gap)
+					gap = nil
+				}
+				continue
+			}
+
+			if gap == nil {
+				gap = &Gap{
+					Symbol:       symbol,
+					From:         s.start,
+					DataProvider: bars.dataProvider,
+				}
+			}
+			gap.To = s.end
+			gap.Bars += 1
+		}
+		if gap != nil {
+			gaps = append(gaps, *gap)
+		}
+	}
+
+	return gaps
+}
+
+// Fill re-requests the missing bars from the data provider that delivered
+// the existing bars of the symbol and upserts them into the table.
+func Fill(params *Params, gaps []Gap) error {
+	for _, gap := range gaps {
+		apiParams := &dataprovider.ApiParams{
+			Tickers:  []string{gap.Symbol},
+			Timespan: params.Timespan.Value,
+			Quantity: params.quantity(),
+			From:     gap.From,
+			To:       gap.To,
+			Table:    params.Table,
+		}
+
+		var err error
+		switch dataprovider.Provider(gap.DataProvider) {
+		case dataprovider.Polygon:
+			err = polygon.ImportMarketData(apiParams)
+		case dataprovider.TwelveData:
+			err = twelvedata.ImportMarketData(apiParams)
+		default:
+			err = errors.New(fmt.Sprintf(
+				"Cannot re-request data from data provider '%s'",
+				gap.DataProvider,
+			))
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf(
+				"Filling gap of '%s' from %s to %s failed: %v",
+				gap.Symbol,
+				gap.From.Format(time.RFC3339),
+				gap.To.Format(time.RFC3339),
+				err,
+			))
+		}
+	}
+
+	return nil
+}
+
+func queryBars(
+	db database.DB,
+	params *Params,
+) (map[string]*symbolBars, error) {
+	query := fmt.Sprintf(
+		"SELECT symbol, time, data_provider FROM %s WHERE TRUE",
+		pgx.Identifier{params.Table}.Sanitize(),
+	)
+	var args []any
+	if len(params.Symbols) > 0 {
+		// DuckDB cannot bind lists, so every symbol gets its own parameter
+		var placeholders []string
+		for _, symbol := range params.Symbols {
+			args = append(args, symbol)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		query += fmt.Sprintf(
+			" AND symbol IN (%s)",
+			strings.Join(placeholders, ", "),
+		)
+	}
+	if !params.From.IsZero() {
+		args = append(args, params.From)
+		query += fmt.Sprintf(" AND time >= $%d", len(args))
+	}
+	if !params.To.IsZero() {
+		args = append(args, params.To)
+		query += fmt.Sprintf(" AND time <= $%d", len(args))
+	}
+	query += " ORDER BY symbol, time"
+
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	symbols := make(map[string]*symbolBars)
+	for rows.Next() {
+		var symbol, dataProvider string
+		var t time.Time
+		err = rows.Scan(&symbol, &t, &dataProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		bars, ok := symbols[symbol]
+		if !ok {
+			bars = &symbolBars{first: t, bars: make(map[int64]bool)}
+			symbols[symbol] = bars
+		}
+		bars.last = t
+		bars.dataProvider = dataProvider
+		bars.bars[barKey(params, t)] = true
+	}
+
+	return symbols, rows.Err()
+}
+
+func (params *Params) quantity() int {
+	if params.Quantity == 0 {
+		return 1
+	}
+	return params.Quantity
+}
+
+func stepDuration(params *Params) time.Duration {
+	step := time.Hour
+	switch params.Timespan.Value {
+	case dataprovider.Second:
+		step = time.Second
+	case dataprovider.Minute:
+		step = time.Minute
+	}
+	return step * time.Duration(params.quantity())
+}
+
+// barDate returns the trading date of a daily or weekly bar. Bars without a
+// time component are stored at midnight UTC, all other bars are assigned to
+// the date in the exchange's timezone.
+func barDate(params *Params, t time.Time) time.Time {
+	utc := t.UTC()
+	if utc.Hour() != 0 || utc.Minute() != 0 || utc.Second() != 0 {
+		t = t.In(params.Calendar.Location())
+	} else {
+		t = utc
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart returns the monday of the week of the date. Providers that start
+// their weeks on sundays are mapped to the following trading week.
+func weekStart(date time.Time) time.Time {
+	if date.Weekday() == time.Sunday {
+		return date.AddDate(0, 0, 1)
+	}
+	return date.AddDate(0, 0, -int(date.Weekday()-time.Monday))
+}
+
+// periodStart returns the first date of the calendar based period of the
+// date, e.g. the first of january for yearly bars.
+func periodStart(timespan dataprovider.TimespanInfo, date time.Time) time.Time {
+	month := date.Month()
+	if timespan.Months > 1 {
+		month -= (month - 1) % time.Month(timespan.Months)
+	}
+	return time.Date(date.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func barKey(params *Params, t time.Time) int64 {
+	switch {
+	case params.Timespan.Value == dataprovider.Day:
+		return barDate(params, t).Unix()
+	case params.Timespan.Value == dataprovider.Week:
+		return weekStart(barDate(params, t)).Unix()
+	case params.Timespan.IsCalendarBased():
+		return periodStart(params.Timespan, barDate(params, t)).Unix()
+	default:
+		return t.Truncate(stepDuration(params)).Unix()
+	}
+}
+
+func expectedSlots(params *Params, from, to time.Time) []slot {
+	if params.Timespan.Value == dataprovider.Day ||
+		params.Timespan.Value == dataprovider.Week ||
+		params.Timespan.IsCalendarBased() {
+		return expectedDateSlots(params, from, to)
+	}
+
+	step := stepDuration(params)
+
+	var slots []slot
+	for _, session := range params.Calendar.Sessions(from, to) {
+		for t := session.Open.Truncate(step); t.Before(session.Close); t = t.Add(step) {
+			if t.Before(from) || t.After(to) {
+				continue
+			}
+			slots = append(slots, slot{
+				key:   t.Unix(),
+				start: t,
+				end:   t.Add(step),
+			})
+		}
+	}
+
+	return slots
+}
+
+func expectedDateSlots(params *Params, from, to time.Time) []slot {
+	fromDate := barDate(params, from)
+	toDate := barDate(params, to)
+
+	var slots []slot
+	// The sessions are requested with a day of margin since the bar dates
+	// don't need to be in the exchange's timezone.
+	sessions := params.Calendar.Sessions(
+		fromDate.AddDate(0, 0, -1),
+		toDate.AddDate(0, 0, 1),
+	)
+	for _, session := range sessions {
+		date := time.Date(
+			session.Date.Year(),
+			session.Date.Month(),
+			session.Date.Day(),
+			0, 0, 0, 0,
+			time.UTC,
+		)
+		if date.Before(fromDate) || date.After(toDate) {
+			continue
+		}
+
+		if params.Timespan.Value == dataprovider.Day {
+			slots = append(slots, slot{
+				key:   date.Unix(),
+				start: date,
+				end:   date.AddDate(0, 0, 1),
+			})
+			continue
+		}
+
+		start := weekStart(date)
+		end := start.AddDate(0, 0, 7)
+		if params.Timespan.IsCalendarBased() {
+			start = periodStart(params.Timespan, date)
+			end = start.AddDate(0, params.Timespan.Months, 0)
+		}
+		if len(slots) > 0 && slots[len(slots)-1].key == start.Unix() {
+			continue
+		}
+		slots = append(slots, slot{
+			key:   start.Unix(),
+			start: start,
+			end:   end,
+		})
+	}
+
+	return slots
+}
+
+func sortedKeys(symbols map[string]*symbolBars) []string {
+	var keys []string
+	for key := range symbols {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,399 @@
+package gaps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/premia-ai/cli/internal/calendar"
+	"github.com/premia-ai/cli/internal/dataprovider"
+)
+
+// testCalendar is a fixed calendar so that updates of the bundled calendars
+// don't change the expectations. 2023-07-04 is a holiday, 2023-07-03 closes
+// early and daylight saving time starts on 2023-03-12.
+const testCalendar = `{
+	"exchange": "TEST",
+	"name": "Test Exchange",
+	"timezone": "America/New_York",
+	"from": "2023-01-01",
+	"to": "2023-12-31",
+	"open": "09:30",
+	"close": "16:00",
+	"earlyClose": "13:00",
+	"holidays": ["2023-07-04"],
+	"earlyCloses": ["2023-07-03"]
+}`
+
+func testParams(t *testing.T, timespanUnit string, quantity int) *Params {
+	t.Helper()
+
+	testCal, err := calendar.Parse([]byte(testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timespan, err := dataprovider.GetTimespanInfo(timespanUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Params{
+		Table:    "test_candles",
+		Timespan: timespan,
+		Quantity: quantity,
+		Calendar: testCal,
+	}
+}
+
+// localTime parses a time like "2023-07-03 09:30" in the calendar's timezone.
+func localTime(t *testing.T, params *Params, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.ParseInLocation(
+		"2006-01-02 15:04",
+		value,
+		params.Calendar.Location(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// sessionBars returns the start of every bar between open and close of the
+// days, missing bars are left out.
+func sessionBars(
+	t *testing.T,
+	params *Params,
+	days [][2]string,
+	missing ...string,
+) []time.Time {
+	t.Helper()
+
+	skipped := make(map[time.Time]bool)
+	for _, value := range missing {
+		skipped[localTime(t, params, value)] = true
+	}
+
+	var bars []time.Time
+	for _, day := range days {
+		closeTime := localTime(t, params, day[1])
+		for bar := localTime(t, params, day[0]); bar.Before(closeTime); bar = bar.Add(stepDuration(params)) {
+			if !skipped[bar] {
+				bars = append(bars, bar)
+			}
+		}
+	}
+	return bars
+}
+
+func newSymbolBars(params *Params, bars []time.Time) *symbolBars {
+	symbol := &symbolBars{
+		first:        bars[0],
+		last:         bars[len(bars)-1],
+		dataProvider: string(dataprovider.Polygon),
+		bars:         make(map[int64]bool),
+	}
+	for _, bar := range bars {
+		symbol.bars[barKey(params, bar)] = true
+	}
+	return symbol
+}
+
+func TestExpectedSlots(t *testing.T) {
+	tests := []struct {
+		name         string
+		timespanUnit string
+		quantity     int
+		from         string
+		to           string
+		// firstSlots are the UTC starts of the slots that don't continue the
+		// previous slot, i.e. that follow a closed market
+		firstSlots []string
+		lastEnd    string
+		slots      int
+	}{
+		{
+			name:         "skips holidays and ends early closes early",
+			timespanUnit: "minute",
+			quantity:     30,
+			from:         "2023-07-03 00:00",
+			to:           "2023-07-05 23:59",
+			firstSlots:   []string{"2023-07-03T13:30:00Z", "2023-07-05T13:30:00Z"},
+			lastEnd:      "2023-07-05T20:00:00Z",
+			// 7 bars until the early close and 13 bars of the full session
+			slots: 7 + 13,
+		},
+		{
+			name:         "follows the start of daylight saving time",
+			timespanUnit: "minute",
+			quantity:     30,
+			from:         "2023-03-10 00:00",
+			to:           "2023-03-13 23:59",
+			firstSlots:   []string{"2023-03-10T14:30:00Z", "2023-03-13T13:30:00Z"},
+			lastEnd:      "2023-03-13T20:00:00Z",
+			slots:        13 + 13,
+		},
+		{
+			name:         "builds bars of a quantity greater than one",
+			timespanUnit: "minute",
+			quantity:     5,
+			from:         "2023-07-05 00:00",
+			to:           "2023-07-05 23:59",
+			firstSlots:   []string{"2023-07-05T13:30:00Z"},
+			lastEnd:      "2023-07-05T20:00:00Z",
+			slots:        78,
+		},
+		{
+			name:         "skips holidays of daily bars",
+			timespanUnit: "day",
+			quantity:     1,
+			from:         "2023-07-03 00:00",
+			to:           "2023-07-07 00:00",
+			firstSlots:   []string{"2023-07-03T00:00:00Z", "2023-07-05T00:00:00Z"},
+			lastEnd:      "2023-07-08T00:00:00Z",
+			slots:        4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := testParams(t, test.timespanUnit, test.quantity)
+			slots := expectedSlots(
+				params,
+				localTime(t, params, test.from),
+				localTime(t, params, test.to),
+			)
+
+			if len(slots) != test.slots {
+				t.Fatalf("got %d slots, want %d", len(slots), test.slots)
+			}
+
+			var firstSlots []string
+			for i, s := range slots {
+				if i == 0 || s.start.Sub(slots[i-1].end) > 0 {
+					firstSlots = append(firstSlots, s.start.UTC().Format(time.RFC3339))
+				}
+			}
+			if len(firstSlots) != len(test.firstSlots) {
+				t.Fatalf("sessions start at %v, want %v", firstSlots, test.firstSlots)
+			}
+			for i := range firstSlots {
+				if firstSlots[i] != test.firstSlots[i] {
+					t.Errorf("sessions start at %v, want %v", firstSlots, test.firstSlots)
+					break
+				}
+			}
+
+			lastEnd := slots[len(slots)-1].end.UTC().Format(time.RFC3339)
+			if lastEnd != test.lastEnd {
+				t.Errorf("last slot ends at %s, want %s", lastEnd, test.lastEnd)
+			}
+		})
+	}
+}
+
+func TestDetectGaps(t *testing.T) {
+	type expectedGap struct {
+		from string
+		to   string
+		bars int
+	}
+
+	tests := []struct {
+		name         string
+		timespanUnit string
+		quantity     int
+		days         [][2]string
+		missing      []string
+		gaps         []expectedGap
+	}{
+		{
+			name:         "doesn't expect bars on holidays and after early closes",
+			timespanUnit: "minute",
+			quantity:     30,
+			days: [][2]string{
+				{"2023-07-03 09:30", "2023-07-03 13:00"},
+				{"2023-07-05 09:30", "2023-07-05 16:00"},
+			},
+		},
+		{
+			name:         "joins missing bars around a holiday",
+			timespanUnit: "minute",
+			quantity:     30,
+			days: [][2]string{
+				{"2023-07-03 09:30", "2023-07-03 13:00"},
+				{"2023-07-05 09:30", "2023-07-05 16:00"},
+			},
+			missing: []string{"2023-07-03 12:30", "2023-07-05 09:30", "2023-07-05 10:00"},
+			gaps: []expectedGap{
+				{"2023-07-03 12:30", "2023-07-05 10:30", 3},
+			},
+		},
+		{
+			name:         "keeps sessions in local time across daylight saving time",
+			timespanUnit: "minute",
+			quantity:     30,
+			days: [][2]string{
+				{"2023-03-10 09:30", "2023-03-10 16:00"},
+				{"2023-03-13 09:30", "2023-03-13 16:00"},
+			},
+			missing: []string{"2023-03-13 15:30"},
+			gaps: []expectedGap{
+				{"2023-03-13 15:30", "2023-03-13 16:00", 1},
+			},
+		},
+		{
+			name:         "joins missing bars of a quantity greater than one",
+			timespanUnit: "minute",
+			quantity:     5,
+			days: [][2]string{
+				{"2023-07-05 09:30", "2023-07-05 16:00"},
+			},
+			missing: []string{"2023-07-05 10:00", "2023-07-05 10:05", "2023-07-05 11:00"},
+			gaps: []expectedGap{
+				{"2023-07-05 10:00", "2023-07-05 10:10", 2},
+				{"2023-07-05 11:00", "2023-07-05 11:05", 1},
+			},
+		},
+		{
+			name:         "reports missing hours of a quantity greater than one",
+			timespanUnit: "hour",
+			quantity:     2,
+			// Hour bars are aligned to UTC, so the first bar starts before
+			// the open at 09:30
+			days: [][2]string{
+				{"2023-07-05 08:00", "2023-07-05 16:00"},
+				{"2023-07-06 08:00", "2023-07-06 16:00"},
+			},
+			missing: []string{"2023-07-06 12:00"},
+			gaps: []expectedGap{
+				{"2023-07-06 12:00", "2023-07-06 14:00", 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := testParams(t, test.timespanUnit, test.quantity)
+			bars := sessionBars(t, params, test.days, test.missing...)
+			symbols := map[string]*symbolBars{
+				"TEST": newSymbolBars(params, bars),
+			}
+			// Missing bars at the start of the range are only found within
+			// an explicit range
+			params.From = localTime(t, params, test.days[0][0])
+			params.To = localTime(t, params, test.days[len(test.days)-1][1])
+
+			gaps := detectGaps(params, symbols)
+			if len(gaps) != len(test.gaps) {
+				t.Fatalf("got %d gaps %v, want %d", len(gaps), gaps, len(test.gaps))
+			}
+
+			for i, gap := range gaps {
+				expected := test.gaps[i]
+				from := localTime(t, params, expected.from)
+				to := localTime(t, params, expected.to)
+				if !gap.From.Equal(from) || !gap.To.Equal(to) || gap.Bars != expected.bars {
+					t.Errorf(
+						"gap %d is %s - %s with %d bars, want %s - %s with %d bars",
+						i,
+						gap.From,
+						gap.To,
+						gap.Bars,
+						from,
+						to,
+						expected.bars,
+					)
+				}
+				if gap.Symbol != "TEST" || gap.DataProvider != string(dataprovider.Polygon) {
+					t.Errorf("gap %d belongs to %s of %s", i, gap.Symbol, gap.DataProvider)
+				}
+			}
+		})
+	}
+}
+
+func TestAddMissingSymbols(t *testing.T) {
+	tests := []struct {
+		name         string
+		dataProvider string
+		from         string
+		wantErr      bool
+	}{
+		{
+			name:         "reports the whole range of a symbol without bars",
+			dataProvider: string(dataprovider.TwelveData),
+			from:         "2023-07-03 09:30",
+		},
+		{
+			name:         "needs a data provider",
+			dataProvider: "",
+			from:         "2023-07-03 09:30",
+			wantErr:      true,
+		},
+		{
+			name:         "needs a range",
+			dataProvider: string(dataprovider.TwelveData),
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := testParams(t, "minute", 30)
+			params.Symbols = []string{"TEST", "MISSING"}
+			if test.from != "" {
+				params.From = localTime(t, params, test.from)
+			}
+			params.To = localTime(t, params, "2023-07-05 16:00")
+
+			bars := sessionBars(t, params, [][2]string{
+				{"2023-07-03 09:30", "2023-07-03 13:00"},
+				{"2023-07-05 09:30", "2023-07-05 16:00"},
+			})
+			symbols := map[string]*symbolBars{
+				"TEST": newSymbolBars(params, bars),
+			}
+
+			err := addMissingSymbols(params, symbols, func(symbol string) (string, error) {
+				if symbol != "MISSING" {
+					t.Errorf("looked up the data provider of %s", symbol)
+				}
+				return test.dataProvider, nil
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gaps := detectGaps(params, symbols)
+			if len(gaps) != 1 {
+				t.Fatalf("got %d gaps %v, want 1", len(gaps), gaps)
+			}
+
+			gap := gaps[0]
+			from := localTime(t, params, "2023-07-03 09:30")
+			to := localTime(t, params, "2023-07-05 16:00")
+			if gap.Symbol != "MISSING" ||
+				gap.DataProvider != test.dataProvider ||
+				!gap.From.Equal(from) ||
+				!gap.To.Equal(to) ||
+				gap.Bars != 7+13 {
+				t.Errorf(
+					"got gap of %s from %s to %s with %d bars of %s",
+					gap.Symbol,
+					gap.From,
+					gap.To,
+					gap.Bars,
+					gap.DataProvider,
+				)
+			}
+		})
+	}
+}
@@ -3,13 +3,15 @@ package helper
 import (
 	"context"
 	"encoding/csv"
-	"errors"
-	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/premia-ai/cli/internal/database"
 )
 
 var MarketDataColumnNames = []string{
@@ -27,15 +29,38 @@ var MarketDataColumnNames = []string{
 type MarketDataRow struct {
 	Time         time.Time
 	Symbol       string
-	Open         string
-	Close        string
-	High         string
-	Low          string
-	Volume       string
+	Open         pgtype.Numeric
+	Close        pgtype.Numeric
+	High         pgtype.Numeric
+	Low          pgtype.Numeric
+	Volume       pgtype.Numeric
 	Currency     string
 	DataProvider string
 }
 
+// ParseDecimal keeps the exact decimal representation of a value, an empty
+// string is mapped to NULL.
+func ParseDecimal(value string) (pgtype.Numeric, error) {
+	var numeric pgtype.Numeric
+	if value == "" {
+		return numeric, nil
+	}
+
+	err := numeric.ScanScientific(value)
+	if err != nil {
+		return numeric, err
+	}
+
+	return numeric, nil
+}
+
+func FloatToDecimal(value float64) pgtype.Numeric {
+	// The shortest representation that round-trips avoids binary floating
+	// point artifacts like 0.30000000000000004
+	numeric, _ := ParseDecimal(strconv.FormatFloat(value, 'f', -1, 64))
+	return numeric
+}
+
 func (p *MarketDataRow) Slice() []any {
 	values := make([]any, len(MarketDataColumnNames))
 	for idx, columnName := range MarketDataColumnNames {
@@ -100,6 +125,42 @@ func GetCsvColumn(filePath string, column string) ([]string, error) {
 	return result, nil
 }
 
+var intervalRegexp = regexp.MustCompile(
+	`^(\d+) (second|minute|hour|day|week|month|year)s?$`,
+)
+
+// IsInterval checks that the value is a simple Postgres interval like
+// "7 days" which is safe to be rendered into SQL.
+func IsInterval(value string) bool {
+	return intervalRegexp.MatchString(value)
+}
+
+var intervalUnitSeconds = map[string]int{
+	"second": 1,
+	"minute": 60,
+	"hour":   60 * 60,
+	"day":    24 * 60 * 60,
+	"week":   7 * 24 * 60 * 60,
+	"month":  30 * 24 * 60 * 60,
+	"year":   365 * 24 * 60 * 60,
+}
+
+// IntervalSeconds returns the length of an interval that passes IsInterval
+// in seconds, months and years are approximated like Postgres does.
+func IntervalSeconds(value string) int {
+	match := intervalRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+
+	quantity, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+
+	return quantity * intervalUnitSeconds[match[2]]
+}
+
 func IsInSlice(slice []string, value string) bool {
 	for _, sliceValue := range slice {
 		if value == sliceValue {
@@ -111,30 +172,116 @@ func IsInSlice(slice []string, value string) bool {
 }
 
 func CopyFileToTable(filePath, baseTable string) error {
-	postgresUrl := os.Getenv("POSTGRES_URL")
-	if postgresUrl == "" {
-		return errors.New("Please set POSTGRES_URL environment variable")
+	db, err := database.Open()
+	if err != nil {
+		return err
 	}
 
-	conn, err := pgx.Connect(context.Background(), postgresUrl)
-	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"Unable to connect to database: %v\n", err,
-		))
+	return db.CopyCsv(context.Background(), baseTable, filePath)
+}
+
+var FxRateColumnNames = []string{
+	"time",
+	"base_currency",
+	"quote_currency",
+	"rate",
+	"data_provider",
+}
+
+type FxRateRow struct {
+	Time          time.Time
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          pgtype.Numeric
+	DataProvider  string
+}
+
+func (p *FxRateRow) Slice() []any {
+	pgTimestamptz := pgtype.Timestamptz{}
+	pgTimestamptz.Time = p.Time
+	pgTimestamptz.Valid = true
+
+	return []any{
+		pgTimestamptz,
+		p.BaseCurrency,
+		p.QuoteCurrency,
+		p.Rate,
+		p.DataProvider,
 	}
-	defer conn.Close(context.Background())
+}
 
-	_, err = conn.Exec(
-		context.Background(),
-		fmt.Sprintf(
-			"COPY %s FROM '%s' DELIMITER ',' CSV HEADER;",
-			baseTable,
-			filePath,
-		),
-	)
+var SymbolColumnNames = []string{
+	"symbol",
+	"exchange",
+	"exchange_timezone",
+	"data_provider",
+}
+
+type SymbolRow struct {
+	Symbol           string
+	Exchange         string
+	ExchangeTimezone string
+	DataProvider     string
+}
+
+func (s *SymbolRow) Slice() []any {
+	return []any{
+		s.Symbol,
+		s.Exchange,
+		s.ExchangeTimezone,
+		s.DataProvider,
+	}
+}
+
+// UpsertSymbols stores the exchange timezones of the symbols. Databases that
+// were initialized before the symbols table existed are skipped.
+func UpsertSymbols(db database.DB, table string, symbols []SymbolRow) error {
+	exists, err := db.TableExists(context.Background(), table)
 	if err != nil {
 		return err
 	}
+	if !exists || len(symbols) == 0 {
+		return nil
+	}
+
+	var rows [][]any
+	for _, symbol := range symbols {
+		rows = append(rows, symbol.Slice())
+	}
 
-	return nil
+	return db.Upsert(
+		context.Background(),
+		table,
+		SymbolColumnNames,
+		[]string{"symbol"},
+		pgx.CopyFromRows(rows),
+	)
+}
+
+func UpsertMarketData(
+	db database.DB,
+	table string,
+	rows pgx.CopyFromSource,
+) error {
+	return db.Upsert(
+		context.Background(),
+		table,
+		MarketDataColumnNames,
+		[]string{"symbol", "time"},
+		rows,
+	)
+}
+
+func UpsertFxRates(
+	db database.DB,
+	table string,
+	rows pgx.CopyFromSource,
+) error {
+	return db.Upsert(
+		context.Background(),
+		table,
+		FxRateColumnNames,
+		[]string{"base_currency", "quote_currency", "time"},
+		rows,
+	)
 }
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/helper"
+)
+
+// EnableCompression creates and applies a migration that enables native
+// compression for the instrument's base table or updates the compression
+// policy if compression is already enabled.
+func EnableCompression(
+	instrumentType config.InstrumentType,
+	compressAfter string,
+) error {
+	if !helper.IsInterval(compressAfter) {
+		return errors.New(fmt.Sprintf(
+			"'%s' is not a valid interval, please use a format like '7 days'",
+			compressAfter,
+		))
+	}
+
+	configData, err := config.Config()
+	if err != nil {
+		return err
+	}
+
+	err = configData.RequireTimescale("Compression")
+	if err != nil {
+		return err
+	}
+
+	instrumentConfig, ok := configData.Instruments[instrumentType]
+	if !ok {
+		return errors.New(
+			fmt.Sprintf("There is no '%s' table set up.", instrumentType),
+		)
+	}
+
+	data := SqlTemplateData{
+		ReferenceTable: instrumentConfig.BaseTable,
+		CompressAfter:  compressAfter,
+	}
+
+	if instrumentConfig.Compression == nil {
+		up, err := renderTemplate("add_compression.up.template.sql", data)
+		if err != nil {
+			return err
+		}
+		down, err := renderTemplate("add_compression.down.template.sql", data)
+		if err != nil {
+			return err
+		}
+
+		err = createRawMigration("add_compression", up, down)
+		if err != nil {
+			return err
+		}
+	} else if instrumentConfig.Compression.After != compressAfter {
+		up, err := renderTemplate(
+			"update_compression_policy.up.template.sql",
+			data,
+		)
+		if err != nil {
+			return err
+		}
+
+		data.CompressAfter = instrumentConfig.Compression.After
+		down, err := renderTemplate(
+			"update_compression_policy.down.template.sql",
+			data,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = createRawMigration("update_compression_policy", up, down)
+		if err != nil {
+			return err
+		}
+	} else {
+		return nil
+	}
+
+	err = Apply()
+	if err != nil {
+		return err
+	}
+
+	instrumentConfig.Compression = &config.CompressionConfig{
+		After: compressAfter,
+	}
+	return config.UpdateConfig(instrumentType, &instrumentConfig)
+}
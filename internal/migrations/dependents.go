@@ -0,0 +1,614 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+)
+
+// schemaDependent is a view that has to be dropped and recreated to change
+// the columns of the table it's based on.
+type schemaDependent struct {
+	name   string
+	drop   string
+	create string
+	// continuous is set for continuous aggregates, they can only be refreshed
+	// after the migration since refreshes can't run inside a transaction
+	continuous bool
+}
+
+type catalogRelation struct {
+	schema string
+	name   string
+	// kind is the relkind of pg_class or "c" for continuous aggregates
+	kind string
+}
+
+func (r catalogRelation) identifier() string {
+	return pgx.Identifier{r.schema, r.name}.Sanitize()
+}
+
+type continuousAggregateInfo struct {
+	hypertable             catalogRelation
+	materialization        catalogRelation
+	materializedOnly       bool
+	definition             string
+	compressionEnabled     bool
+	refreshStartOffset     *string
+	refreshEndOffset       *string
+	refreshSchedule        string
+	hasRefreshPolicy       bool
+	retentionDropAfter     string
+	hasRetentionPolicy     bool
+	hasCompressionPolicies bool
+}
+
+// configDependents returns the views of the config that are based on the
+// instrument's base table in the order they need to be created. DuckDB
+// doesn't record which views depend on a table, so its views are taken from
+// the config.
+func configDependents(
+	configData *config.ConfigFileData,
+	instrumentType config.InstrumentType,
+) ([]schemaDependent, error) {
+	views, err := dependentViews(configData, instrumentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []schemaDependent
+	for _, view := range views {
+		drop, err := renderTemplate(view.Name+".down.template.sql", view.Data)
+		if err != nil {
+			return nil, err
+		}
+		create, err := renderTemplate(view.Name+".up.template.sql", view.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		dependents = append(dependents, schemaDependent{
+			name:   view.Name,
+			drop:   drop,
+			create: create,
+		})
+	}
+
+	return dependents, nil
+}
+
+// catalogDependents returns all views, materialized views and continuous
+// aggregates that are directly or indirectly based on the table in the order
+// they need to be created. Their definitions, indexes and policies are taken
+// from the database, so views that were created before the config recorded
+// them are found too.
+func catalogDependents(
+	table string,
+	timescale bool,
+) ([]schemaDependent, error) {
+	pool, err := database.Pool()
+	if err != nil {
+		return nil, err
+	}
+
+	continuousAggregates := make(map[catalogRelation]*continuousAggregateInfo)
+	if timescale {
+		continuousAggregates, err = loadContinuousAggregateInfos(pool)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkTimescaleUpgrade(pool, table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var root catalogRelation
+	err = pool.QueryRow(
+		context.Background(),
+		`SELECT n.nspname, c.relname, c.relkind::text
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = $1::regclass;`,
+		table,
+	).Scan(&root.schema, &root.name, &root.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	// Breadth-first search through the views that are based on the table and
+	// the views that are based on those
+	sources := make(map[catalogRelation][]catalogRelation)
+	var found []catalogRelation
+	queue := []catalogRelation{root}
+	for len(queue) > 0 {
+		relation := queue[0]
+		queue = queue[1:]
+
+		dependents, err := directDependents(pool, relation, continuousAggregates)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dependent := range dependents {
+			if _, ok := sources[dependent]; !ok {
+				found = append(found, dependent)
+				queue = append(queue, dependent)
+			}
+			sources[dependent] = append(sources[dependent], relation)
+		}
+	}
+
+	ordered := creationOrder(root, found, sources)
+
+	var result []schemaDependent
+	for _, relation := range ordered {
+		dependent, err := catalogDependent(
+			pool,
+			relation,
+			continuousAggregates[relation],
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dependent)
+	}
+
+	// Aggregates on top of other aggregates are recreated from the buckets of
+	// their source, which retention may have dropped already
+	for _, relation := range ordered {
+		info := continuousAggregates[relation]
+		if info == nil || !info.hasRetentionPolicy {
+			continue
+		}
+		for _, dependent := range ordered {
+			if continuousAggregates[dependent] == nil {
+				continue
+			}
+			for _, source := range sources[dependent] {
+				if source == relation {
+					return nil, errors.New(fmt.Sprintf(
+						"'%s' has a retention policy and '%s' is built on it. Recreating '%s' would lose its buckets of the period that was dropped from '%s' already, so its columns cannot be changed.",
+						relation.name,
+						dependent.name,
+						dependent.name,
+						relation.name,
+					))
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// checkTimescaleUpgrade refuses to change hypertables whose data can't be
+// rebuilt or whose columns can't be changed.
+func checkTimescaleUpgrade(pool *pgxpool.Pool, table string) error {
+	var compressionEnabled, hasRetentionPolicy bool
+	err := pool.QueryRow(
+		context.Background(),
+		`SELECT
+			COALESCE((
+				SELECT compression_enabled FROM timescaledb_information.hypertables
+				WHERE hypertable_name = $1
+			), FALSE),
+			EXISTS (
+				SELECT FROM timescaledb_information.jobs
+				WHERE proc_name = 'policy_retention' AND hypertable_name = $1
+			);`,
+		table,
+	).Scan(&compressionEnabled, &hasRetentionPolicy)
+	if err != nil {
+		return err
+	}
+
+	if compressionEnabled {
+		return errors.New(fmt.Sprintf(
+			`'%s' has compression enabled and the type of compressed columns cannot be changed. Please decompress it and disable compression first:
+  SELECT remove_compression_policy('%s', if_exists => true);
+  SELECT decompress_chunk(c, true) FROM show_chunks('%s') c;
+  ALTER TABLE %s SET (timescaledb.compress = false);`,
+			table,
+			table,
+			table,
+			table,
+		))
+	}
+
+	if hasRetentionPolicy {
+		var hasContinuousAggregates bool
+		err = pool.QueryRow(
+			context.Background(),
+			`SELECT EXISTS (
+				SELECT FROM timescaledb_information.continuous_aggregates
+				WHERE hypertable_name = $1
+			);`,
+			table,
+		).Scan(&hasContinuousAggregates)
+		if err != nil {
+			return err
+		}
+
+		if hasContinuousAggregates {
+			return errors.New(fmt.Sprintf(
+				"'%s' has a retention policy, so its continuous aggregates cannot be rebuilt from the raw data that was dropped already once they are recreated. Its columns cannot be changed without losing the buckets of that period.",
+				table,
+			))
+		}
+	}
+
+	return nil
+}
+
+func loadContinuousAggregateInfos(
+	pool *pgxpool.Pool,
+) (map[catalogRelation]*continuousAggregateInfo, error) {
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT
+			view_schema,
+			view_name,
+			hypertable_schema,
+			hypertable_name,
+			materialization_hypertable_schema,
+			materialization_hypertable_name,
+			materialized_only,
+			view_definition,
+			compression_enabled
+		FROM timescaledb_information.continuous_aggregates;`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := make(map[catalogRelation]*continuousAggregateInfo)
+	for rows.Next() {
+		view := catalogRelation{kind: "c"}
+		var info continuousAggregateInfo
+		err = rows.Scan(
+			&view.schema,
+			&view.name,
+			&info.hypertable.schema,
+			&info.hypertable.name,
+			&info.materialization.schema,
+			&info.materialization.name,
+			&info.materializedOnly,
+			&info.definition,
+			&info.compressionEnabled,
+		)
+		if err != nil {
+			return nil, err
+		}
+		infos[view] = &info
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	for view, info := range infos {
+		err = loadPolicies(pool, info)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"Reading the policies of '%s' failed: %v",
+				view.name,
+				err,
+			))
+		}
+	}
+
+	return infos, nil
+}
+
+func loadPolicies(pool *pgxpool.Pool, info *continuousAggregateInfo) error {
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT
+			proc_name,
+			config->>'start_offset',
+			config->>'end_offset',
+			COALESCE(config->>'drop_after', ''),
+			schedule_interval::text
+		FROM timescaledb_information.jobs
+		WHERE hypertable_schema = $1 AND hypertable_name = $2;`,
+		info.materialization.schema,
+		info.materialization.name,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var procName, dropAfter, schedule string
+		var startOffset, endOffset *string
+		err = rows.Scan(&procName, &startOffset, &endOffset, &dropAfter, &schedule)
+		if err != nil {
+			return err
+		}
+
+		switch procName {
+		case "policy_refresh_continuous_aggregate":
+			info.hasRefreshPolicy = true
+			info.refreshStartOffset = startOffset
+			info.refreshEndOffset = endOffset
+			info.refreshSchedule = schedule
+		case "policy_retention":
+			info.hasRetentionPolicy = true
+			info.retentionDropAfter = dropAfter
+		case "policy_compression":
+			info.hasCompressionPolicies = true
+		}
+	}
+
+	return rows.Err()
+}
+
+// directDependents returns the views that query the relation and the
+// continuous aggregates that are built on it.
+func directDependents(
+	pool *pgxpool.Pool,
+	relation catalogRelation,
+	continuousAggregates map[catalogRelation]*continuousAggregateInfo,
+) ([]catalogRelation, error) {
+	// TimescaleDB's internal views of continuous aggregates are dropped
+	// together with the aggregate
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT DISTINCT n.nspname, v.relname, v.relkind::text
+		FROM pg_depend d
+		JOIN pg_rewrite r ON r.oid = d.objid
+		JOIN pg_class v ON v.oid = r.ev_class
+		JOIN pg_namespace n ON n.oid = v.relnamespace
+		WHERE d.classid = 'pg_rewrite'::regclass
+			AND d.refclassid = 'pg_class'::regclass
+			AND d.refobjid = $1::regclass
+			AND v.oid <> $1::regclass
+			AND n.nspname NOT LIKE '\_timescaledb\_%'
+		ORDER BY 1, 2;`,
+		relation.identifier(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dependents []catalogRelation
+	seen := make(map[catalogRelation]bool)
+	for rows.Next() {
+		var dependent catalogRelation
+		err = rows.Scan(&dependent.schema, &dependent.name, &dependent.kind)
+		if err != nil {
+			return nil, err
+		}
+
+		// Real-time aggregates query the raw data with a regular view
+		continuous := catalogRelation{schema: dependent.schema, name: dependent.name, kind: "c"}
+		if _, ok := continuousAggregates[continuous]; ok {
+			dependent = continuous
+		}
+
+		if !seen[dependent] {
+			seen[dependent] = true
+			dependents = append(dependents, dependent)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// Aggregates are built on the hypertable of the relation, which is the
+	// materialization hypertable for aggregates on top of other aggregates
+	hypertable := relation
+	if info, ok := continuousAggregates[relation]; ok {
+		hypertable = info.materialization
+	}
+
+	var continuous []catalogRelation
+	for view, info := range continuousAggregates {
+		if info.hypertable.schema == hypertable.schema &&
+			info.hypertable.name == hypertable.name &&
+			!seen[view] {
+			continuous = append(continuous, view)
+		}
+	}
+	sort.Slice(continuous, func(i, j int) bool {
+		return continuous[i].name < continuous[j].name
+	})
+
+	return append(dependents, continuous...), nil
+}
+
+// creationOrder sorts the relations so that every relation comes after all
+// relations it's based on.
+func creationOrder(
+	root catalogRelation,
+	found []catalogRelation,
+	sources map[catalogRelation][]catalogRelation,
+) []catalogRelation {
+	created := map[catalogRelation]bool{root: true}
+	var ordered []catalogRelation
+	for len(ordered) < len(found) {
+		progress := false
+		for _, relation := range found {
+			if created[relation] {
+				continue
+			}
+
+			ready := true
+			for _, source := range sources[relation] {
+				ready = ready && created[source]
+			}
+			if !ready {
+				continue
+			}
+
+			created[relation] = true
+			ordered = append(ordered, relation)
+			progress = true
+		}
+
+		// Views can't depend on each other in a cycle, this only guards
+		// against an endless loop
+		if !progress {
+			break
+		}
+	}
+
+	return ordered
+}
+
+func catalogDependent(
+	pool *pgxpool.Pool,
+	relation catalogRelation,
+	info *continuousAggregateInfo,
+) (schemaDependent, error) {
+	identifier := relation.identifier()
+	dependent := schemaDependent{name: relation.name}
+
+	switch relation.kind {
+	case "c":
+		if info.compressionEnabled || info.hasCompressionPolicies {
+			return dependent, errors.New(fmt.Sprintf(
+				"'%s' has compression enabled and would lose it when it's recreated, please decompress it and disable its compression first.",
+				relation.name,
+			))
+		}
+
+		statements := []string{fmt.Sprintf(
+			"CREATE MATERIALIZED VIEW %s\nWITH (timescaledb.continuous, timescaledb.materialized_only = %t) AS\n%s\nWITH NO DATA;",
+			identifier,
+			info.materializedOnly,
+			trimDefinition(info.definition),
+		)}
+		if info.hasRefreshPolicy {
+			statements = append(statements, fmt.Sprintf(
+				"SELECT add_continuous_aggregate_policy('%s',\n    start_offset => %s,\n    end_offset => %s,\n    schedule_interval => INTERVAL '%s');",
+				identifier,
+				intervalOrNull(info.refreshStartOffset),
+				intervalOrNull(info.refreshEndOffset),
+				info.refreshSchedule,
+			))
+		}
+		if info.hasRetentionPolicy {
+			statements = append(statements, fmt.Sprintf(
+				"SELECT add_retention_policy('%s', drop_after => INTERVAL '%s');",
+				identifier,
+				info.retentionDropAfter,
+			))
+		}
+
+		dependent.drop = fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;", identifier)
+		dependent.create = strings.Join(statements, "\n\n")
+		dependent.continuous = true
+	case "m":
+		var definition string
+		var populated bool
+		err := pool.QueryRow(
+			context.Background(),
+			`SELECT pg_get_viewdef($1::regclass, true), ispopulated
+			FROM pg_matviews WHERE schemaname = $2 AND matviewname = $3;`,
+			identifier,
+			relation.schema,
+			relation.name,
+		).Scan(&definition, &populated)
+		if err != nil {
+			return dependent, err
+		}
+
+		statements := []string{fmt.Sprintf(
+			"CREATE MATERIALIZED VIEW %s AS\n%s\nWITH NO DATA;",
+			identifier,
+			trimDefinition(definition),
+		)}
+
+		indexes, err := indexDefinitions(pool, relation)
+		if err != nil {
+			return dependent, err
+		}
+		statements = append(statements, indexes...)
+
+		// Views that haven't been filled yet are left for 'premia aggregate
+		// refresh'
+		if populated {
+			statements = append(
+				statements,
+				fmt.Sprintf("REFRESH MATERIALIZED VIEW %s;", identifier),
+			)
+		}
+
+		dependent.drop = fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;", identifier)
+		dependent.create = strings.Join(statements, "\n\n")
+	default:
+		var definition string
+		err := pool.QueryRow(
+			context.Background(),
+			"SELECT pg_get_viewdef($1::regclass, true);",
+			identifier,
+		).Scan(&definition)
+		if err != nil {
+			return dependent, err
+		}
+
+		dependent.drop = fmt.Sprintf("DROP VIEW IF EXISTS %s;", identifier)
+		dependent.create = fmt.Sprintf(
+			"CREATE VIEW %s AS\n%s;",
+			identifier,
+			trimDefinition(definition),
+		)
+	}
+
+	return dependent, nil
+}
+
+func indexDefinitions(
+	pool *pgxpool.Pool,
+	relation catalogRelation,
+) ([]string, error) {
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT indexdef FROM pg_indexes
+		WHERE schemaname = $1 AND tablename = $2
+		ORDER BY indexname;`,
+		relation.schema,
+		relation.name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []string
+	for rows.Next() {
+		var index string
+		err = rows.Scan(&index)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index+";")
+	}
+
+	return indexes, rows.Err()
+}
+
+// trimDefinition removes the semicolon that view definitions end with.
+func trimDefinition(definition string) string {
+	return strings.TrimSuffix(strings.TrimSpace(definition), ";")
+}
+
+func intervalOrNull(value *string) string {
+	if value == nil {
+		return "NULL"
+	}
+
+	return fmt.Sprintf("INTERVAL '%s'", *value)
+}
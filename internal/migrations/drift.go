@@ -0,0 +1,371 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+
+	"github.com/premia-ai/cli/internal/calendar"
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/drift"
+	"github.com/premia-ai/cli/internal/helper"
+)
+
+// previousCompressionPattern finds the interval that an update of the
+// compression policy replaced in its down migration.
+var previousCompressionPattern = regexp.MustCompile(
+	`add_compression_policy\('([a-z0-9_]+)',\s*INTERVAL\s*'([^']+)'\)`,
+)
+
+type templateFile struct {
+	up   string
+	down string
+}
+
+// DetectDrift reports the drift of drift.Detect and migration files that
+// differ from the templates rendered from the config.
+func DetectDrift() ([]drift.Drift, error) {
+	drifts, err := drift.Detect()
+	if err != nil {
+		return nil, err
+	}
+
+	templateDrifts, err := detectTemplateDrift()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(drifts, templateDrifts...), nil
+}
+
+// detectTemplateDrift renders the migrations that 'premia init' creates
+// for the config and compares them with the migration files. Migrations that
+// later commands created from values that the config doesn't keep, e.g.
+// retention policies, aren't compared.
+func detectTemplateDrift() ([]drift.Drift, error) {
+	configData, err := config.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := templateFiles(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := configMigrations(configData, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Migrations that can be rendered with different values, e.g. the volume
+	// types of add_candles, are grouped by the object they create
+	groups := make(map[string][]templateMigration)
+	var groupKeys []string
+	for _, migration := range expected {
+		key := migration.Name + " (" + migration.Object + ")"
+		if _, ok := groups[key]; !ok {
+			groupKeys = append(groupKeys, key)
+		}
+		groups[key] = append(groups[key], migration)
+	}
+
+	var fileNames []string
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	var drifts []drift.Drift
+	matchedGroups := make(map[string]bool)
+	unmatchedTemplates := make(map[string]bool)
+	for _, fileName := range fileNames {
+		file := files[fileName]
+		templateName := migrationTemplateName(fileName)
+
+		compared := false
+		matched := false
+		for _, key := range groupKeys {
+			if groups[key][0].Name != templateName {
+				continue
+			}
+			compared = true
+
+			for _, migration := range groups[key] {
+				up, err := renderTemplate(migration.Name+".up.template.sql", migration.Data)
+				if err != nil {
+					return nil, err
+				}
+				if up != file.up {
+					continue
+				}
+
+				matched = true
+				matchedGroups[key] = true
+
+				down, err := renderTemplate(migration.Name+".down.template.sql", migration.Data)
+				if err != nil {
+					return nil, err
+				}
+				if file.down != "" && down != file.down {
+					drifts = append(drifts, drift.Drift{
+						Problem: drift.Modified,
+						Kind:    drift.KindMigration,
+						Object:  strings.Replace(fileName, ".up.", ".down.", 1),
+						Detail:  fmt.Sprintf("differs from the template rendered from the config for '%s'", migration.Object),
+					})
+				}
+				break
+			}
+			if matched {
+				break
+			}
+		}
+
+		if compared && !matched {
+			unmatchedTemplates[templateName] = true
+			drifts = append(drifts, drift.Drift{
+				Problem: drift.Modified,
+				Kind:    drift.KindMigration,
+				Object:  fileName,
+				Detail:  "differs from the template rendered from the config",
+			})
+		}
+	}
+
+	for _, key := range groupKeys {
+		// Edited files are reported above already
+		if matchedGroups[key] || unmatchedTemplates[groups[key][0].Name] {
+			continue
+		}
+
+		drifts = append(drifts, drift.Drift{
+			Problem: drift.Missing,
+			Kind:    drift.KindMigration,
+			Object:  key,
+			Detail:  "defined by the config, but no migration file matches the rendered template",
+		})
+	}
+
+	return drifts, nil
+}
+
+// templateFiles returns the content of the up migrations and their down
+// migrations by the name of the up migration.
+func templateFiles(migrationsDir string) (map[string]templateFile, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	upNames := make(map[uint]string)
+	downNames := make(map[uint]string)
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if migration.Direction == source.Up {
+			upNames[migration.Version] = entry.Name()
+		} else {
+			downNames[migration.Version] = entry.Name()
+		}
+	}
+
+	files := make(map[string]templateFile)
+	for version, upName := range upNames {
+		up, err := os.ReadFile(path.Join(migrationsDir, upName))
+		if err != nil {
+			return nil, err
+		}
+
+		file := templateFile{up: string(up)}
+		if downName, ok := downNames[version]; ok {
+			down, err := os.ReadFile(path.Join(migrationsDir, downName))
+			if err != nil {
+				return nil, err
+			}
+			file.down = string(down)
+		}
+
+		files[upName] = file
+	}
+
+	return files, nil
+}
+
+// migrationTemplateName returns the template of a migration file, e.g.
+// add_candles for 3_add_candles.up.sql.
+func migrationTemplateName(fileName string) string {
+	parts := strings.SplitN(fileName, "_", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return strings.TrimSuffix(parts[1], ".up.sql")
+}
+
+// configMigrations returns the migrations that 'premia init' creates for the
+// config. Values that later commands change without changing the original
+// migration are rendered with all values they could have had.
+func configMigrations(
+	configData *config.ConfigFileData,
+	migrationsDir string,
+) ([]templateMigration, error) {
+	var migrations []templateMigration
+	if configData.DatabaseBackend() == config.BackendTimescale {
+		migrations = append(migrations, templateMigration{
+			Name:   "add_timescale",
+			Object: "timescaledb",
+		})
+	}
+
+	calendars, err := calendar.All()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations = append(
+		migrations,
+		templateMigration{
+			Name:   "add_trading_calendars",
+			Object: "trading_calendars",
+			Data:   SqlTemplateData{Calendars: calendars},
+		},
+		templateMigration{Name: "add_symbols", Object: "symbols"},
+	)
+
+	if configData.ReportingCurrency != "" {
+		migrations = append(migrations, templateMigration{
+			Name:   "add_fx_rates",
+			Object: "fx_rates",
+		})
+	}
+
+	previousCompressions, err := previousCompressionIntervals(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var instrumentTypes []string
+	for instrumentType := range configData.Instruments {
+		instrumentTypes = append(instrumentTypes, string(instrumentType))
+	}
+	sort.Strings(instrumentTypes)
+
+	for _, name := range instrumentTypes {
+		instrumentType := config.InstrumentType(name)
+		instrumentConfig := configData.Instruments[instrumentType]
+
+		// 'premia upgrade volume' changes the column with a new migration.
+		// Installs from before the volume type was configurable use INT.
+		volumeTypes := []string{config.VolumeInt}
+		if instrumentConfig.VolumeType != "" &&
+			!helper.IsInSlice(volumeTypes, instrumentConfig.VolumeType) {
+			volumeTypes = append(volumeTypes, instrumentConfig.VolumeType)
+		}
+		for _, volumeType := range config.VolumeTypes {
+			if !helper.IsInSlice(volumeTypes, volumeType) {
+				volumeTypes = append(volumeTypes, volumeType)
+			}
+		}
+		for _, volumeType := range volumeTypes {
+			migrations = append(migrations, templateMigration{
+				Name:   "add_candles",
+				Object: instrumentConfig.BaseTable,
+				Data: SqlTemplateData{
+					InstrumentType: instrumentType,
+					Quantity:       instrumentConfig.Quantity,
+					TimeUnit:       instrumentConfig.TimespanUnit,
+					VolumeType:     volumeType,
+				},
+			})
+		}
+
+		if instrumentConfig.Compression != nil {
+			// Updates of the compression policy are separate migrations
+			compressAfters := append(
+				[]string{instrumentConfig.Compression.After},
+				previousCompressions[instrumentConfig.BaseTable]...,
+			)
+			for _, compressAfter := range compressAfters {
+				migrations = append(migrations, templateMigration{
+					Name:   "add_compression",
+					Object: instrumentConfig.BaseTable,
+					Data: SqlTemplateData{
+						ReferenceTable: instrumentConfig.BaseTable,
+						CompressAfter:  compressAfter,
+					},
+				})
+			}
+		}
+
+		switch instrumentType {
+		case config.Stocks:
+			migrations = append(migrations, templateMigration{
+				Name:   "add_companies",
+				Object: "companies",
+			})
+			if instrumentConfig.AdjustedTable != "" {
+				migrations = append(
+					migrations,
+					templateMigration{Name: "add_splits", Object: "splits"},
+					templateMigration{Name: "add_dividends", Object: "dividends"},
+				)
+			}
+		case config.Options:
+			migrations = append(migrations, templateMigration{
+				Name:   "add_contracts",
+				Object: "contracts",
+			})
+		}
+
+		views, err := dependentViews(configData, instrumentType)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, views...)
+	}
+
+	return migrations, nil
+}
+
+// previousCompressionIntervals returns the compression intervals that updates
+// of the compression policies replaced by table.
+func previousCompressionIntervals(
+	migrationsDir string,
+) (map[string][]string, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string][]string)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), "_update_compression_policy.down.sql") {
+			continue
+		}
+
+		content, err := os.ReadFile(path.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range previousCompressionPattern.FindAllStringSubmatch(string(content), -1) {
+			intervals[match[1]] = append(intervals[match[1]], match[2])
+		}
+	}
+
+	return intervals, nil
+}
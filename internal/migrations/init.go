@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
 	"strconv"
@@ -12,13 +13,16 @@ import (
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 
+	"github.com/premia-ai/cli/internal/aggregates"
+	"github.com/premia-ai/cli/internal/calendar"
 	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/credentials"
+	"github.com/premia-ai/cli/internal/database"
 	"github.com/premia-ai/cli/internal/dataprovider"
 	"github.com/premia-ai/cli/internal/dataprovider/polygon"
 	"github.com/premia-ai/cli/internal/dataprovider/twelvedata"
+	"github.com/premia-ai/cli/internal/drift"
 	"github.com/premia-ai/cli/internal/helper"
 	"github.com/premia-ai/cli/resource"
 )
@@ -28,6 +32,26 @@ type SqlTemplateData struct {
 	Quantity       int
 	TimeUnit       string
 	ReferenceTable string
+	Exchange       string
+	Currency       string
+	VolumeType     string
+	CompressAfter  string
+	DropAfter      string
+	Refresh        *config.RefreshConfig
+	TimeColumn     string
+	// Window is the number of bars used by window functions of features
+	Window int
+	// View is the name of the feature's view
+	View string
+	// BucketInterval is the aggregate's bucket width as a Postgres interval
+	BucketInterval string
+	// BucketTimezone is set if buckets are aligned to the exchange's timezone
+	BucketTimezone string
+	// BucketMonths is the bucket width of calendar-based aggregates in months
+	BucketMonths int
+	// Timezone of the instrument's exchange
+	Timezone  string
+	Calendars []*calendar.Calendar
 }
 
 var migrationVersion = 1
@@ -57,62 +81,125 @@ func getFeatureNames() ([]string, error) {
 // TODO: Implement dry-run
 // TODO: Implement verbose
 func Initialize() error {
-	postgresUrl := os.Getenv("POSTGRES_URL")
-	if postgresUrl == "" {
-		return errors.New("Please set POSTGRES_URL environment variable")
+	// Connection problems are reported before any questions are asked
+	_, err := database.Open()
+	if err != nil {
+		return err
 	}
 
 	migrationsDir, err := config.MigrationsDir(true)
 
+	backend, err := config.Backend()
+	if err != nil {
+		return err
+	}
+
+	if backend == config.BackendTimescale {
+		err = CreateMigration(
+			"add_timescale",
+			SqlTemplateData{},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	calendars, err := calendar.All()
+	if err != nil {
+		return err
+	}
+
 	err = CreateMigration(
-		"add_timescale",
-		SqlTemplateData{},
+		"add_trading_calendars",
+		SqlTemplateData{Calendars: calendars},
 	)
 	if err != nil {
 		return err
 	}
 
-	add_stocks, err := askBoolQuestion("Do you want to store stock price data?")
-
+	err = CreateMigration("add_symbols", SqlTemplateData{})
 	if err != nil {
 		return err
 	}
 
-	if add_stocks == false {
-		return nil
+	reportingCurrency, err := addFxMigrations()
+	if err != nil {
+		return err
 	}
 
-	err = addInstrumentMigrations(config.Stocks)
+	add_stocks, err := askBoolQuestion("Do you want to store stock price data?")
 	if err != nil {
 		return err
 	}
 
+	if add_stocks {
+		err = addInstrumentMigrations(config.Stocks, reportingCurrency)
+		if err != nil {
+			return err
+		}
+	}
+
 	add_options, err := askBoolQuestion(
 		"Do you want to store option price data?",
 	)
-
 	if err != nil {
 		return err
 	}
 
-	if add_options == false {
-		return nil
+	if add_options {
+		err = addInstrumentMigrations(config.Options, reportingCurrency)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = addInstrumentMigrations(config.Options)
+	err = applyMigrations(migrationsDir)
 	if err != nil {
 		return err
 	}
 
-	err = applyMigrations(migrationsDir, postgresUrl)
+	return nil
+}
+
+// addFxMigrations returns the reporting currency or an empty string if the
+// user doesn't want to convert prices.
+func addFxMigrations() (string, error) {
+	addFxRates, err := askBoolQuestion(
+		"Do you want to store FX rates to convert prices into a reporting currency?",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if !addFxRates {
+		return "", nil
+	}
+
+	reportingCurrency, err := askInputQuestion(
+		"What is your reporting currency? (e.g. USD)",
+	)
 	if err != nil {
-		return err
+		return "", err
 	}
+	reportingCurrency = strings.ToUpper(strings.TrimSpace(reportingCurrency))
 
-	return nil
+	err = CreateMigration("add_fx_rates", SqlTemplateData{})
+	if err != nil {
+		return "", err
+	}
+
+	err = config.SetReportingCurrency(reportingCurrency)
+	if err != nil {
+		return "", err
+	}
+
+	return reportingCurrency, nil
 }
 
-func addInstrumentMigrations(instrumentType config.InstrumentType) error {
+func addInstrumentMigrations(
+	instrumentType config.InstrumentType,
+	reportingCurrency string,
+) error {
 	var timespanUnits []string
 	for _, timespan := range dataprovider.Timespans {
 		timespanUnits = append(timespanUnits, timespan.Unit)
@@ -134,43 +221,90 @@ func addInstrumentMigrations(instrumentType config.InstrumentType) error {
 		}
 	}
 
+	quantity, err := askQuantityQuestion(fmt.Sprintf(
+		"How many %ss does one data point span? (e.g. 5 for 5 %s bars)",
+		timespan.Unit,
+		timespan.Unit,
+	))
+	if err != nil {
+		return err
+	}
+
+	volumeType, err := askSelectQuestion(
+		"Which type should be used to store volumes? (NUMERIC keeps fractional volumes, e.g. of crypto)",
+		config.VolumeTypes,
+	)
+	if err != nil {
+		return err
+	}
+
+	exchanges, err := calendar.Exchanges()
+	if err != nil {
+		return err
+	}
+
+	exchange, err := askSelectQuestion(
+		"Which exchange's trading calendar should be used?",
+		exchanges,
+	)
+	if err != nil {
+		return err
+	}
+
 	err = CreateMigration(
 		"add_candles",
 		SqlTemplateData{
 			InstrumentType: instrumentType,
-			Quantity:       1,
+			Quantity:       quantity,
 			TimeUnit:       timespan.Unit,
+			VolumeType:     volumeType,
 		},
 	)
 	if err != nil {
 		return err
 	}
 
-	// Create aggregate table
-	// TODO: The user should be able to create multiple aggregate tables
 	baseTable := fmt.Sprintf(
-		"%s_1_%s_candles",
+		"%s_%d_%s_candles",
 		instrumentType,
+		quantity,
 		timespan.Unit,
 	)
 
-	err = config.UpdateConfig(
-		instrumentType,
-		&config.InstrumentConfig{
-			BaseTable:    baseTable,
-			TimespanUnit: timespan.Unit,
-		},
-	)
+	instrumentConfig := config.InstrumentConfig{
+		BaseTable:    baseTable,
+		TimespanUnit: timespan.Unit,
+		Quantity:     quantity,
+		Exchange:     exchange,
+		VolumeType:   volumeType,
+	}
+
+	backend, err := config.Backend()
 	if err != nil {
 		return err
 	}
 
+	// Compression and refresh policies are only available with TimescaleDB
+	if backend == config.BackendTimescale {
+		instrumentConfig.Compression, err = addCompressionMigration(baseTable)
+		if err != nil {
+			return err
+		}
+	}
+
 	switch instrumentType {
 	case config.Stocks:
 		err = CreateMigration(
 			"add_companies",
 			SqlTemplateData{},
 		)
+		if err != nil {
+			return err
+		}
+
+		instrumentConfig.AdjustedTable, err = addCorporateActionMigrations(
+			baseTable,
+		)
 	case config.Options:
 		err = CreateMigration(
 			"add_contracts",
@@ -181,38 +315,132 @@ func addInstrumentMigrations(instrumentType config.InstrumentType) error {
 		return err
 	}
 
-	addAggregate, err := askBoolQuestion(
-		"Do you want to create an aggregate based on your raw data?",
+	if reportingCurrency != "" {
+		convertedTables := []string{baseTable}
+		if instrumentConfig.AdjustedTable != "" {
+			convertedTables = append(
+				convertedTables,
+				instrumentConfig.AdjustedTable,
+			)
+		}
+
+		for _, table := range convertedTables {
+			err = CreateMigration(
+				"add_converted_candles",
+				SqlTemplateData{
+					ReferenceTable: table,
+					Currency:       reportingCurrency,
+				},
+			)
+			if err != nil {
+				return err
+			}
+
+			instrumentConfig.ConvertedTables = append(
+				instrumentConfig.ConvertedTables,
+				fmt.Sprintf("%s_%s", table, strings.ToLower(reportingCurrency)),
+			)
+		}
+	}
+
+	aggregateTimespanUnits := append(
+		[]string{timespan.Unit},
+		timespan.BiggerUnits...,
 	)
+	aggregateQuestion := "Do you want to create an aggregate based on your raw data?"
+	for {
+		addAggregate, err := askBoolQuestion(aggregateQuestion)
+		if err != nil {
+			return err
+		}
+		if !addAggregate {
+			break
+		}
+		aggregateQuestion = "Do you want to create another aggregate?"
 
-	if addAggregate {
 		aggregateTimespanUnit, err := askSelectQuestion(
 			"Which duration should the table have?",
-			timespan.BiggerUnits,
+			aggregateTimespanUnits,
+		)
+		if err != nil {
+			return err
+		}
+
+		aggregateTimespanInfo, err := dataprovider.GetTimespanInfo(
+			aggregateTimespanUnit,
 		)
 		if err != nil {
 			return err
 		}
 
-		var aggregateTimespanInfo dataprovider.TimespanInfo
-		for _, f := range dataprovider.Timespans {
-			if f.Unit == aggregateTimespanUnit {
-				aggregateTimespanInfo = f
+		aggregateQuantity, err := askQuantityQuestion(fmt.Sprintf(
+			"How many %ss should one bucket span?",
+			aggregateTimespanInfo.Unit,
+		))
+		if err != nil {
+			return err
+		}
+
+		if !timespan.CanAggregate(
+			quantity,
+			aggregateTimespanInfo,
+			aggregateQuantity,
+		) {
+			fmt.Printf(
+				"%d %s buckets cannot be built from %d %s bars.\n\n",
+				aggregateQuantity,
+				aggregateTimespanInfo.Unit,
+				quantity,
+				timespan.Unit,
+			)
+			continue
+		}
+
+		aggregate := config.AggregateConfig{
+			Table: fmt.Sprintf(
+				"%s_%d_%s_candles",
+				instrumentType,
+				aggregateQuantity,
+				aggregateTimespanInfo.Unit,
+			),
+			TimespanUnit: aggregateTimespanInfo.Unit,
+			Quantity:     aggregateQuantity,
+			ReferenceTable: cheapestSource(
+				&instrumentConfig,
+				aggregateTimespanInfo,
+				aggregateQuantity,
+			),
+		}
+		if hasAggregate(&instrumentConfig, aggregate.Table) {
+			fmt.Printf("The aggregate '%s' already exists.\n\n", aggregate.Table)
+			continue
+		}
+
+		if backend == config.BackendTimescale {
+			aggregate.Refresh, err = askRefreshConfig(&aggregate)
+			if err != nil {
+				return err
 			}
 		}
 
-		err = CreateMigration(
-			"add_aggregate_candles",
-			SqlTemplateData{
-				InstrumentType: instrumentType,
-				Quantity:       1,
-				TimeUnit:       aggregateTimespanInfo.Unit,
-				ReferenceTable: baseTable,
-			},
+		aggregateData, err := aggregateTemplateData(
+			instrumentType,
+			&instrumentConfig,
+			&aggregate,
 		)
 		if err != nil {
 			return err
 		}
+
+		err = CreateMigration("add_aggregate_candles", aggregateData)
+		if err != nil {
+			return err
+		}
+
+		instrumentConfig.Aggregates = append(
+			instrumentConfig.Aggregates,
+			aggregate,
+		)
 	}
 
 	// Create feature table
@@ -238,20 +466,316 @@ func addInstrumentMigrations(instrumentType config.InstrumentType) error {
 			return err
 		}
 
+		referenceTable := baseTable
+		if instrumentConfig.AdjustedTable != "" {
+			useAdjustedTable, err := askBoolQuestion(
+				"Should the feature be based on split and dividend adjusted prices?",
+			)
+			if err != nil {
+				return err
+			}
+
+			if useAdjustedTable {
+				referenceTable = instrumentConfig.AdjustedTable
+			}
+		}
+
+		timezone, err := exchangeTimezone(exchange)
+		if err != nil {
+			return err
+		}
+
+		feature := config.FeatureConfig{
+			Name:           featureName,
+			TimespanUnit:   timespan.Unit,
+			Quantity:       quantity,
+			ReferenceTable: referenceTable,
+		}
+		if featureName == "moving_averages" {
+			feature.Window, err = askQuantityQuestion(
+				"Over how many bars should the average be calculated?",
+			)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = CreateMigration(
 			featureName,
 			SqlTemplateData{
 				InstrumentType: instrumentType,
-				Quantity:       1,
-				TimeUnit:       timespan.Unit,
-				ReferenceTable: baseTable,
+				Quantity:       feature.Quantity,
+				TimeUnit:       feature.TimespanUnit,
+				ReferenceTable: feature.ReferenceTable,
+				Exchange:       exchange,
+				Timezone:       timezone,
+				Window:         feature.WindowSize(),
+				View:           feature.View(instrumentType),
 			},
 		)
 		if err != nil {
 			return err
 		}
+
+		instrumentConfig.Features = append(instrumentConfig.Features, feature)
+	}
+
+	return config.UpdateConfig(instrumentType, &instrumentConfig)
+}
+
+func aggregateTemplateData(
+	instrumentType config.InstrumentType,
+	instrumentConfig *config.InstrumentConfig,
+	aggregate *config.AggregateConfig,
+) (SqlTemplateData, error) {
+	// Aggregates that are built on top of other aggregates read their bucket
+	// column instead of the base table's time column
+	timeColumn := "time"
+	for _, source := range instrumentConfig.Aggregates {
+		if source.Table == aggregate.ReferenceTable {
+			timeColumn = "bucket"
+		}
+	}
+
+	timespan, err := dataprovider.GetTimespanInfo(aggregate.TimespanUnit)
+	if err != nil {
+		return SqlTemplateData{}, err
+	}
+
+	// Days and bigger buckets start at midnight in the exchange's timezone,
+	// otherwise the bars of non-UTC exchanges are split across two days.
+	// Continuous aggregates only support a constant timezone, so symbols are
+	// bucketed in the timezone of the instrument's exchange.
+	bucketTimezone := ""
+	if timespan.IsCalendarBased() ||
+		timespan.Seconds*aggregate.Quantity >= 24*60*60 {
+		bucketTimezone, err = exchangeTimezone(instrumentConfig.Exchange)
+		if err != nil {
+			return SqlTemplateData{}, err
+		}
+	}
+
+	return SqlTemplateData{
+		InstrumentType: instrumentType,
+		Quantity:       aggregate.Quantity,
+		TimeUnit:       aggregate.TimespanUnit,
+		ReferenceTable: aggregate.ReferenceTable,
+		TimeColumn:     timeColumn,
+		BucketInterval: timespan.Interval(aggregate.Quantity),
+		BucketTimezone: bucketTimezone,
+		BucketMonths:   timespan.Months * aggregate.Quantity,
+		Refresh:        aggregate.RefreshConfig(),
+	}, nil
+}
+
+func exchangeTimezone(exchange string) (string, error) {
+	if exchange == "" {
+		exchange = calendar.DefaultExchange
+	}
+
+	exchangeCalendar, err := calendar.Get(exchange)
+	if err != nil {
+		return "", err
+	}
+
+	return exchangeCalendar.Timezone, nil
+}
+
+func hasAggregate(
+	instrumentConfig *config.InstrumentConfig,
+	table string,
+) bool {
+	for _, aggregate := range instrumentConfig.Aggregates {
+		if aggregate.Table == table {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cheapestSource returns the table with the biggest buckets that an aggregate
+// of the timespan can be built from. Aggregates on top of other aggregates are
+// hierarchical continuous aggregates which require TimescaleDB 2.9 or newer.
+func cheapestSource(
+	instrumentConfig *config.InstrumentConfig,
+	timespan dataprovider.TimespanInfo,
+	quantity int,
+) string {
+	source := instrumentConfig.BaseTable
+	sourceWidth := 0
+	baseTimespan, err := dataprovider.GetTimespanInfo(instrumentConfig.TimespanUnit)
+	if err == nil {
+		sourceWidth = baseTimespan.Seconds * instrumentConfig.BarQuantity()
+	}
+
+	for _, aggregate := range instrumentConfig.Aggregates {
+		aggregateTimespan, err := dataprovider.GetTimespanInfo(
+			aggregate.TimespanUnit,
+		)
+		if err != nil {
+			continue
+		}
+
+		width := aggregateTimespan.Seconds * aggregate.Quantity
+		if width > sourceWidth &&
+			aggregateTimespan.CanAggregate(aggregate.Quantity, timespan, quantity) {
+			source = aggregate.Table
+			sourceWidth = width
+		}
+	}
+
+	return source
+}
+
+// askRefreshConfig returns nil if the user keeps the default refresh policy.
+func askRefreshConfig(
+	aggregate *config.AggregateConfig,
+) (*config.RefreshConfig, error) {
+	defaultConfig := aggregate.RefreshConfig()
+	customizeRefresh, err := askBoolQuestion(fmt.Sprintf(
+		"Do you want to customize when the aggregate is refreshed? (default: every %s for the range from %s to %s ago)",
+		defaultConfig.ScheduleInterval,
+		defaultConfig.StartOffset,
+		defaultConfig.EndOffset,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if !customizeRefresh {
+		return nil, nil
+	}
+
+	bucketSeconds := helper.IntervalSeconds(aggregate.Interval())
+	for {
+		refreshConfig := config.RefreshConfig{}
+		intervals := []struct {
+			field    *string
+			question string
+		}{
+			{
+				&refreshConfig.StartOffset,
+				"How long ago should the refreshed range start? (e.g. 3 days)",
+			},
+			{
+				&refreshConfig.EndOffset,
+				"How long ago should the refreshed range end? (e.g. 1 day)",
+			},
+			{
+				&refreshConfig.ScheduleInterval,
+				"How often should the aggregate be refreshed? (e.g. 1 day)",
+			},
+		}
+		for _, interval := range intervals {
+			for !helper.IsInterval(*interval.field) {
+				*interval.field, err = askInputQuestion(interval.question)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// TimescaleDB rejects refresh windows that don't cover at least two
+		// buckets
+		startSeconds := helper.IntervalSeconds(refreshConfig.StartOffset)
+		endSeconds := helper.IntervalSeconds(refreshConfig.EndOffset)
+		if startSeconds <= endSeconds {
+			fmt.Printf(
+				"The refreshed range has to start before it ends, %s ago is not before %s ago.\n\n",
+				refreshConfig.StartOffset,
+				refreshConfig.EndOffset,
+			)
+			continue
+		}
+		if startSeconds < endSeconds+2*bucketSeconds {
+			fmt.Printf(
+				"The refreshed range from %s to %s ago has to cover at least two buckets of %s.\n\n",
+				refreshConfig.StartOffset,
+				refreshConfig.EndOffset,
+				aggregate.Interval(),
+			)
+			continue
+		}
+
+		return &refreshConfig, nil
 	}
-	return nil
+}
+
+// addCompressionMigration returns nil if the user doesn't want to compress
+// the base table.
+func addCompressionMigration(
+	baseTable string,
+) (*config.CompressionConfig, error) {
+	addCompression, err := askBoolQuestion(
+		"Do you want to enable compression for your raw data?",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !addCompression {
+		return nil, nil
+	}
+
+	var compressAfter string
+	for {
+		compressAfter, err = askInputQuestion(
+			"After which interval should data be compressed? (e.g. 7 days)",
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if helper.IsInterval(compressAfter) {
+			break
+		}
+	}
+
+	err = CreateMigration(
+		"add_compression",
+		SqlTemplateData{
+			ReferenceTable: baseTable,
+			CompressAfter:  compressAfter,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.CompressionConfig{After: compressAfter}, nil
+}
+
+// addCorporateActionMigrations returns the name of the adjusted candles view
+// or an empty string if the user doesn't want to store corporate actions.
+func addCorporateActionMigrations(baseTable string) (string, error) {
+	addCorporateActions, err := askBoolQuestion(
+		"Do you want to store splits and dividends to adjust your prices?",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if !addCorporateActions {
+		return "", nil
+	}
+
+	for _, templateName := range []string{"add_splits", "add_dividends"} {
+		err = CreateMigration(templateName, SqlTemplateData{})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = CreateMigration(
+		"add_adjusted_candles",
+		SqlTemplateData{ReferenceTable: baseTable},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return baseTable + "_adjusted", nil
 }
 
 // TODO: Make Seed work with both stocks and options, right now it works just
@@ -268,21 +792,34 @@ func Seed() error {
 		return err
 	}
 
-	var timespan dataprovider.TimespanInfo
-	for _, f := range dataprovider.Timespans {
-		// TODO: There needs to be a fix to avoid failure when stocks tables
-		// are not set up
-		if f.Unit == configData.Instruments[config.Stocks].TimespanUnit {
-			timespan = f
-		}
-	}
-
 	// TODO: Move this to a Seed function and call it from the cmd directly
 	if shouldSeedDb {
-		dataProviders := []string{
-			string(dataprovider.Polygon),
-			string(dataprovider.TwelveData),
-			string(dataprovider.Csv),
+		stocksConfig, err := configData.Instrument(config.Stocks)
+		if err != nil {
+			return err
+		}
+
+		timespan, err := dataprovider.GetTimespanInfo(stocksConfig.TimespanUnit)
+		if err != nil {
+			return err
+		}
+
+		// Only offer the providers that can deliver the base table's bars
+		quantity := stocksConfig.BarQuantity()
+		var dataProviders []string
+		for _, provider := range dataprovider.SupportingProviders(
+			string(config.Stocks),
+			timespan.Value,
+			quantity,
+		) {
+			dataProviders = append(dataProviders, string(provider))
+		}
+		if len(dataProviders) == 0 {
+			return errors.New(fmt.Sprintf(
+				"No data provider offers %d %s bars of stocks",
+				quantity,
+				timespan.Unit,
+			))
 		}
 
 		provider, err := askSelectQuestion(
@@ -293,7 +830,19 @@ func Seed() error {
 			return err
 		}
 
-		if provider == dataProviders[0] {
+		capabilities, err := dataprovider.GetCapabilities(
+			dataprovider.Provider(provider),
+		)
+		if err != nil {
+			return err
+		}
+
+		err = askApiKey(dataprovider.Provider(provider))
+		if err != nil {
+			return err
+		}
+
+		if provider == string(dataprovider.Polygon) {
 			ticker, err := askInputQuestion(
 				"What is the ticker of the equity you would like to download?",
 			)
@@ -311,6 +860,10 @@ func Seed() error {
 			if err != nil {
 				return err
 			}
+			err = capabilities.ValidateHistory(fromTime)
+			if err != nil {
+				return err
+			}
 
 			to, err := askInputQuestion(
 				"What should the end date of the entries be?",
@@ -323,20 +876,33 @@ func Seed() error {
 				return err
 			}
 
-			err = polygon.ImportMarketData(&dataprovider.ApiParams{
+			apiParams := &dataprovider.ApiParams{
 				Tickers:  []string{ticker},
 				From:     fromTime,
 				To:       toTime,
 				Timespan: timespan.Value,
-				Quantity: 1,
-				Table:    configData.Instruments[config.Stocks].BaseTable,
-			})
+				Quantity: quantity,
+				Table:    stocksConfig.BaseTable,
+			}
+			err = polygon.ImportMarketData(apiParams)
 			if err != nil {
 				return err
 			}
 
-			// TODO: don't allow twelvedata when the timespan is in seconds
-		} else if provider == dataProviders[1] {
+			// Keep the corporate actions in sync with the prices since the
+			// adjusted candles are computed from both. Back-adjusting a
+			// candle needs every split and dividend after it, so they are
+			// imported up to now instead of the end of the prices.
+			if stocksConfig.AdjustedTable != "" {
+				corporateActionParams := *apiParams
+				corporateActionParams.To = time.Now()
+				err = polygon.ImportCorporateActions(&corporateActionParams)
+				if err != nil {
+					return err
+				}
+			}
+
+		} else if provider == string(dataprovider.TwelveData) {
 			shouldUseCsv, err := askBoolQuestion("Do you want to use a CSV file to select tickers for seeding?")
 			if err != nil {
 				return err
@@ -375,6 +941,10 @@ func Seed() error {
 			if err != nil {
 				return err
 			}
+			err = capabilities.ValidateHistory(fromTime)
+			if err != nil {
+				return err
+			}
 
 			to, err := askInputQuestion(
 				"What should the end date of the entries be?",
@@ -390,15 +960,15 @@ func Seed() error {
 			err = twelvedata.ImportMarketData(&dataprovider.ApiParams{
 				Tickers:  tickers,
 				Timespan: timespan.Value,
-				Quantity: 1,
+				Quantity: quantity,
 				From:     fromTime,
 				To:       toTime,
-				Table:    configData.Instruments[config.Stocks].BaseTable,
+				Table:    stocksConfig.BaseTable,
 			})
 			if err != nil {
 				return err
 			}
-		} else if provider == dataProviders[2] {
+		} else if provider == string(dataprovider.Csv) {
 			seedFilePath, err := askInputQuestion(
 				"What is the path to your CSV file?",
 			)
@@ -406,9 +976,21 @@ func Seed() error {
 				return err
 			}
 
-			// TODO: There needs to be a fix to avoid failure when stocks tables
-			// are not set up
-			err = helper.CopyFileToTable(seedFilePath, configData.Instruments[config.Stocks].BaseTable)
+			err = helper.CopyFileToTable(seedFilePath, stocksConfig.BaseTable)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Materialized views aren't refreshed by policies like continuous
+		// aggregates, so they are filled right away
+		if configData.DatabaseBackend() == config.BackendPostgres {
+			var tables []string
+			for _, aggregate := range stocksConfig.Aggregates {
+				tables = append(tables, aggregate.Table)
+			}
+
+			err = aggregates.RefreshViews(tables)
 			if err != nil {
 				return err
 			}
@@ -417,12 +999,54 @@ func Seed() error {
 	return nil
 }
 
-func applyMigrations(migrationsPath string, databaseUrl string) error {
-	// file:// needs to be added otherwise the New method is throwing an error
-	m, err := migrate.New("file://"+migrationsPath, databaseUrl)
+// askApiKey asks for the API key of the provider if none is stored yet.
+func askApiKey(provider dataprovider.Provider) error {
+	var name string
+	switch provider {
+	case dataprovider.Polygon:
+		name = credentials.Polygon
+	case dataprovider.TwelveData:
+		name = credentials.TwelveData
+	default:
+		return nil
+	}
+
+	_, err := credentials.Get(name)
+	if err == nil {
+		return nil
+	}
+
+	apiKey, err := credentials.ReadSecret(
+		fmt.Sprintf("What is your %s API key?", provider),
+	)
+	if err != nil {
+		return err
+	}
+
+	storeApiKey, err := askBoolQuestion(
+		"Do you want to store the API key encrypted for later use?",
+	)
+	if err != nil {
+		return err
+	}
+
+	if storeApiKey {
+		return credentials.Set(name, apiKey)
+	}
+	return credentials.SetForProcess(name, apiKey)
+}
+
+func applyMigrations(migrationsPath string) error {
+	m, err := database.Migrate(migrationsPath)
 	if err != nil {
 		return err
 	}
+	defer m.Close()
+
+	previousVersion, _, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return err
+	}
 
 	err = m.Up()
 	if err == migrate.ErrNoChange {
@@ -434,7 +1058,16 @@ func applyMigrations(migrationsPath string, databaseUrl string) error {
 		fmt.Println("Successfully applied migrations.")
 	}
 
-	return nil
+	version, _, err := m.Version()
+	if err == migrate.ErrNilVersion {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// 'premia drift' uses the checksums to detect edited migrations and
+	// objects that were changed by hand
+	return drift.RecordChecksums(migrationsPath, previousVersion, version, false)
 }
 
 func askBoolQuestion(question string) (bool, error) {
@@ -487,6 +1120,29 @@ func askSelectQuestion(question string, options []string) (string, error) {
 	}
 }
 
+// askQuantityQuestion asks for a positive multiplier, an empty response
+// defaults to 1.
+func askQuantityQuestion(question string) (int, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf(question + " (default: 1)\n\n>> ")
+
+		scanner.Scan()
+		response := strings.TrimSpace(scanner.Text())
+		if scanner.Err() != nil {
+			return 0, scanner.Err()
+		}
+		if response == "" {
+			return 1, nil
+		}
+
+		quantity, err := strconv.Atoi(response)
+		if err == nil && quantity > 0 {
+			return quantity, nil
+		}
+	}
+}
+
 func askInputQuestion(question string) (string, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -546,34 +1202,87 @@ func createPartialMigration(
 	version int,
 	data SqlTemplateData,
 ) error {
+	migration, err := renderTemplate(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	return writeMigration(getMigrationName(templateName, version), migration)
+}
+
+func renderTemplate(templateName string, data SqlTemplateData) (string, error) {
 	funcMap := template.FuncMap{
-		"sub": func(a, b int) int { return a - b },
+		"sub":      func(a, b int) int { return a - b },
+		"sqlDates": sqlDates,
+		"lower":    strings.ToLower,
+	}
+
+	templatePattern, err := templatePath(templateName)
+	if err != nil {
+		return "", err
 	}
+
 	migration, err := template.New(templateName).Funcs(funcMap).ParseFS(
-		resource.Fs, path.Join("*/*", templateName),
+		resource.Fs, templatePattern,
 	)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	migrationName := getMigrationName(
-		templateName,
-		version,
-	)
+	var result strings.Builder
+	err = migration.Execute(&result, data)
+	if err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
 
+// templatePath returns the pattern of the backend's variant of the template
+// if there is one and the pattern of the TimescaleDB template otherwise.
+func templatePath(templateName string) (string, error) {
+	backend, err := config.Backend()
+	if err != nil {
+		return "", err
+	}
+
+	if backend != config.BackendTimescale {
+		variantPattern := path.Join(
+			resource.TemplatesPath,
+			backend,
+			"*",
+			templateName,
+		)
+		matches, err := fs.Glob(resource.Fs, variantPattern)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return variantPattern, nil
+		}
+	}
+
+	return path.Join(resource.TemplatesPath, "*", templateName), nil
+}
+
+func writeMigration(migrationName string, content string) error {
 	migrationsDir, err := config.MigrationsDir(true)
 	if err != nil {
 		return err
 	}
 
-	f, err := os.Create(
+	return os.WriteFile(
 		path.Join(migrationsDir, migrationName),
+		[]byte(content),
+		0666,
 	)
-	if err != nil {
-		return err
-	}
+}
 
-	migration.Execute(f, data)
+func sqlDates(dates []string) string {
+	var quotedDates []string
+	for _, date := range dates {
+		quotedDates = append(quotedDates, fmt.Sprintf("'%s'", date))
+	}
 
-	return nil
+	return fmt.Sprintf("ARRAY[%s]::date[]", strings.Join(quotedDates, ", "))
 }
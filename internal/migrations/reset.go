@@ -0,0 +1,580 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/drift"
+)
+
+var ErrResetAborted = errors.New("Reset aborted, nothing was dropped or deleted.")
+
+var dropExtensionPattern = regexp.MustCompile(
+	`(?is)\bDROP\s+EXTENSION\s+(?:IF\s+EXISTS\s+)?"?([a-z_][a-z0-9_]*)"?[^;]*;?`,
+)
+
+// decompressPattern and disableCompressionPattern find the statements of
+// add_compression.down that decompress a table before its compression is
+// disabled.
+var decompressPattern = regexp.MustCompile(
+	`(?is)\bSELECT\s+decompress_chunk\(.*?\bshow_chunks\('([a-z_][a-z0-9_]*)'\)[^;]*;`,
+)
+var disableCompressionPattern = regexp.MustCompile(
+	`(?is)\bALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?"?([a-z_][a-z0-9_]*)"?\s+SET\s*\(\s*timescaledb\.compress\s*=\s*false\s*\)\s*;`,
+)
+
+type ResetOptions struct {
+	// ExportDir is set if the tables should be exported as CSV files before
+	// they are dropped
+	ExportDir string
+	// Yes skips the confirmation
+	Yes bool
+}
+
+type resetObject struct {
+	name   string
+	kind   string
+	exists bool
+	// rows is only counted for tables
+	rows int64
+	// usedBy lists the objects outside of the reset that use an extension
+	usedBy []string
+}
+
+type resetStep struct {
+	file    string
+	version uint
+	content string
+	// skipDecompression holds the tables that the down migration would
+	// decompress although a later down migration drops them
+	skipDecompression []string
+}
+
+// Reset undoes 'premia init': it runs the down migrations of all applied
+// migrations in reverse order and deletes the config, the migrations and
+// the temporary files of the active profile.
+func Reset(options ResetOptions) error {
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"%v\nThe profile wasn't initialized, there is nothing to reset.",
+			err,
+		))
+	}
+
+	m, err := database.Migrate(migrationsDir)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		version = 0
+	} else if err != nil {
+		return err
+	}
+	if dirty {
+		return errors.New(fmt.Sprintf(
+			"Migration %d failed and left the database in a dirty state, please fix it by hand first.\nRun 'premia doctor' for details.",
+			version,
+		))
+	}
+
+	downFiles, err := appliedDownFiles(migrationsDir, version)
+	if err != nil {
+		return err
+	}
+
+	steps, err := resetSteps(migrationsDir, downFiles)
+	if err != nil {
+		return err
+	}
+
+	objects, err := resetObjects(steps)
+	if err != nil {
+		return err
+	}
+
+	setupFiles, err := config.SetupFiles()
+	if err != nil {
+		return err
+	}
+
+	exportDir := ""
+	if options.ExportDir != "" {
+		exportDir, err = filepath.Abs(options.ExportDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	printResetPlan(steps, objects, setupFiles, exportDir)
+
+	if !options.Yes {
+		confirmed, err := askBoolQuestion("Do you want to continue?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return ErrResetAborted
+		}
+	}
+
+	if exportDir != "" {
+		err = exportTables(objects, exportDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(steps) > 0 {
+		err = runResetSteps(m, steps)
+		if err != nil {
+			return errors.New(fmt.Sprintf(
+				"Running the down migrations failed: %v\nRun 'premia doctor' to see which migration is applied now.",
+				err,
+			))
+		}
+		fmt.Println("Successfully ran the down migrations.")
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{
+		"schema_migrations",
+		drift.ChecksumsTable,
+		drift.DefinitionsTable,
+	} {
+		err = db.Exec(
+			context.Background(),
+			fmt.Sprintf("DROP TABLE IF EXISTS %s;", pgx.Identifier{table}.Sanitize()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The DuckDB file can only be deleted once it's closed
+	database.Close()
+
+	return config.RemoveSetupFiles()
+}
+
+// appliedDownFiles returns the down files of the migrations up to version
+// in the order in which they are run.
+func appliedDownFiles(migrationsDir string, version uint) ([]string, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	upVersions := make(map[uint]bool)
+	downFiles := make(map[uint]string)
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil || migration.Version > version {
+			continue
+		}
+
+		if migration.Direction == source.Up {
+			upVersions[migration.Version] = true
+		} else {
+			downFiles[migration.Version] = entry.Name()
+		}
+	}
+
+	var versions []uint
+	for upVersion := range upVersions {
+		if _, ok := downFiles[upVersion]; !ok {
+			return nil, errors.New(fmt.Sprintf(
+				"Migration %d has no down file in '%s', so it cannot be undone.",
+				upVersion,
+				migrationsDir,
+			))
+		}
+		versions = append(versions, upVersion)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	var files []string
+	for _, downVersion := range versions {
+		files = append(files, downFiles[downVersion])
+	}
+
+	return files, nil
+}
+
+// resetSteps reads the down files in the order in which they are run and
+// finds the tables whose decompression can be skipped since a later down
+// migration drops them anyway.
+func resetSteps(migrationsDir string, downFiles []string) ([]resetStep, error) {
+	var steps []resetStep
+	for _, downFile := range downFiles {
+		migration, err := source.Parse(downFile)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(path.Join(migrationsDir, downFile))
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, resetStep{
+			file:    downFile,
+			version: migration.Version,
+			content: string(content),
+		})
+	}
+
+	droppedLater := make(map[string]bool)
+	for i := len(steps) - 1; i >= 0; i-- {
+		for _, match := range decompressPattern.FindAllStringSubmatch(steps[i].content, -1) {
+			if droppedLater[match[1]] {
+				steps[i].skipDecompression = append(steps[i].skipDecompression, match[1])
+			}
+		}
+
+		for _, object := range drift.DroppedObjects(steps[i].content) {
+			if object.Kind == drift.KindTable {
+				droppedLater[object.Name] = true
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// resetObjects returns the tables, views, functions and extensions that the
+// down migrations drop.
+func resetObjects(steps []resetStep) ([]resetObject, error) {
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []resetObject
+	dropped := make(map[string]bool)
+	for _, step := range steps {
+		for _, droppedObject := range drift.DroppedObjects(step.content) {
+			// Down migrations may recreate objects that a later one drops
+			if dropped[droppedObject.Name] {
+				continue
+			}
+			dropped[droppedObject.Name] = true
+
+			object := resetObject{
+				name: droppedObject.Name,
+				kind: droppedObject.Kind,
+			}
+
+			if object.kind == drift.KindFunction {
+				object.exists, err = drift.FunctionExists(db, object.name)
+			} else {
+				object.exists, err = db.TableExists(context.Background(), object.name)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if object.kind == drift.KindTable && object.exists {
+				err = db.QueryRow(
+					context.Background(),
+					fmt.Sprintf(
+						"SELECT COUNT(*) FROM %s;",
+						pgx.Identifier{object.name}.Sanitize(),
+					),
+				).Scan(&object.rows)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			objects = append(objects, object)
+		}
+	}
+
+	for _, step := range steps {
+		for _, match := range dropExtensionPattern.FindAllStringSubmatch(step.content, -1) {
+			object := resetObject{
+				name: strings.ToLower(match[1]),
+				kind: "extension",
+			}
+
+			err = db.QueryRow(
+				context.Background(),
+				"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1);",
+				object.name,
+			).Scan(&object.exists)
+			if err != nil {
+				return nil, err
+			}
+
+			if object.exists {
+				object.usedBy, err = extensionUsers(db, object.name, dropped)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// runResetSteps runs the down migrations one after another. Down migrations
+// that skip the decompression of dropped tables or keep an extension that
+// other objects use are run without these statements and their version is
+// set by hand.
+func runResetSteps(m *migrate.Migrate, steps []resetStep) error {
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		sql := step.content
+		for _, table := range step.skipDecompression {
+			sql = removeTableStatements(sql, decompressPattern, table)
+			sql = removeTableStatements(sql, disableCompressionPattern, table)
+		}
+
+		for _, match := range dropExtensionPattern.FindAllStringSubmatch(sql, -1) {
+			usedBy, err := extensionUsers(db, strings.ToLower(match[1]), nil)
+			if err != nil {
+				return err
+			}
+			if len(usedBy) == 0 {
+				continue
+			}
+
+			sql = strings.Replace(sql, match[0], "", 1)
+			fmt.Printf(
+				"Keeping the extension '%s' since it's used by %s.\n",
+				match[1],
+				strings.Join(usedBy, ", "),
+			)
+		}
+
+		if sql == step.content {
+			err = m.Steps(-1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.TrimSpace(sql) != "" {
+			err = db.Exec(context.Background(), sql)
+			if err != nil {
+				return errors.New(fmt.Sprintf("%s: %v", step.file, err))
+			}
+		}
+
+		nextVersion := -1
+		if i+1 < len(steps) {
+			nextVersion = int(steps[i+1].version)
+		}
+		err = m.Force(nextVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeTableStatements(sql string, pattern *regexp.Regexp, table string) string {
+	return pattern.ReplaceAllStringFunc(sql, func(statement string) string {
+		if pattern.FindStringSubmatch(statement)[1] == table {
+			return ""
+		}
+		return statement
+	})
+}
+
+// extensionUsers returns the relations outside of the extension that depend
+// on it, e.g. views that call its functions or hypertables of timescaledb.
+// Relations of the current schema whose names are excluded are left out.
+func extensionUsers(
+	db database.DB,
+	extension string,
+	excluded map[string]bool,
+) ([]string, error) {
+	if db.Backend() == config.BackendDuckDB {
+		return nil, nil
+	}
+
+	pool, err := database.Pool()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT n.nspname, c.relname, n.nspname = current_schema()
+		FROM pg_extension e
+		JOIN pg_depend member
+			ON member.refclassid = 'pg_extension'::regclass
+			AND member.refobjid = e.oid
+			AND member.deptype = 'e'
+		JOIN pg_depend d
+			ON d.refclassid = member.classid
+			AND d.refobjid = member.objid
+			AND d.deptype = 'n'
+			AND d.classid IN ('pg_rewrite'::regclass, 'pg_class'::regclass)
+		JOIN pg_class c ON c.oid = CASE
+			WHEN d.classid = 'pg_rewrite'::regclass
+				THEN (SELECT ev_class FROM pg_rewrite WHERE oid = d.objid)
+			ELSE d.objid
+		END
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE e.extname = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend own
+				WHERE own.classid = 'pg_class'::regclass
+					AND own.objid = c.oid
+					AND own.deptype = 'e'
+			)`
+	if extension == "timescaledb" {
+		query += `
+		UNION
+		SELECT hypertable_schema, hypertable_name, hypertable_schema = current_schema()
+		FROM timescaledb_information.hypertables
+		UNION
+		SELECT view_schema, view_name, view_schema = current_schema()
+		FROM timescaledb_information.continuous_aggregates`
+	}
+
+	rows, err := pool.Query(
+		context.Background(),
+		"SELECT * FROM ("+query+") users ORDER BY 1, 2;",
+		extension,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var schema, name string
+		var current bool
+		err = rows.Scan(&schema, &name, &current)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(schema, "_timescaledb") || (current && excluded[name]) {
+			continue
+		}
+		users = append(users, schema+"."+name)
+	}
+
+	return users, rows.Err()
+}
+
+func printResetPlan(
+	steps []resetStep,
+	objects []resetObject,
+	setupFiles []string,
+	exportDir string,
+) {
+	if len(steps) > 0 {
+		fmt.Println("These down migrations are run in this order:")
+		for _, step := range steps {
+			if len(step.skipDecompression) > 0 {
+				fmt.Printf(
+					"  %s (without decompressing %s, which is dropped afterwards)\n",
+					step.file,
+					strings.Join(step.skipDecompression, ", "),
+				)
+				continue
+			}
+			fmt.Printf("  %s\n", step.file)
+		}
+		fmt.Println()
+	}
+
+	if len(objects) > 0 {
+		fmt.Println("They drop:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, object := range objects {
+			detail := ""
+			if !object.exists {
+				detail = "already missing"
+			} else if len(object.usedBy) > 0 {
+				detail = fmt.Sprintf("kept, used by %s", strings.Join(object.usedBy, ", "))
+			} else if object.kind == drift.KindTable {
+				detail = fmt.Sprintf("%d rows", object.rows)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", object.kind, object.name, detail)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	if exportDir != "" {
+		fmt.Printf("The tables are exported as CSV files to '%s' first.\n\n", exportDir)
+	}
+
+	if len(setupFiles) > 0 {
+		fmt.Println("Afterwards these files are deleted:")
+		for _, setupFile := range setupFiles {
+			fmt.Printf("  %s\n", setupFile)
+		}
+		fmt.Println()
+	}
+}
+
+// exportTables writes every table to <table>.csv in the export directory.
+// Views aren't exported since the migrations recreate them from the tables.
+func exportTables(objects []resetObject, exportDir string) error {
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(exportDir, 0777)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if object.kind != drift.KindTable || !object.exists {
+			continue
+		}
+
+		filePath := path.Join(exportDir, object.name+".csv")
+		err = db.ExportCsv(context.Background(), object.name, filePath)
+		if err != nil {
+			return errors.New(fmt.Sprintf(
+				"Exporting '%s' failed, nothing was dropped: %v",
+				object.name,
+				err,
+			))
+		}
+	}
+
+	fmt.Printf("Successfully exported the tables to '%s'.\n", exportDir)
+	return nil
+}
@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/helper"
+	"github.com/premia-ai/cli/internal/storage"
+)
+
+// SetRetention creates and applies a migration that adds or updates the
+// retention policy of the instrument's base table or one of its aggregates.
+// Raw data is only dropped if an aggregate keeps it in a downsampled form.
+func SetRetention(
+	instrumentType config.InstrumentType,
+	table string,
+	dropAfter string,
+) error {
+	if !helper.IsInterval(dropAfter) {
+		return errors.New(fmt.Sprintf(
+			"'%s' is not a valid interval, please use a format like '90 days'",
+			dropAfter,
+		))
+	}
+
+	configData, err := config.Config()
+	if err != nil {
+		return err
+	}
+
+	err = configData.RequireTimescale("Retention")
+	if err != nil {
+		return err
+	}
+
+	instrumentConfig, ok := configData.Instruments[instrumentType]
+	if !ok {
+		return errors.New(
+			fmt.Sprintf("There is no '%s' table set up.", instrumentType),
+		)
+	}
+
+	var retention **config.RetentionConfig
+	if table == "" || table == instrumentConfig.BaseTable {
+		table = instrumentConfig.BaseTable
+		retention = &instrumentConfig.Retention
+	} else {
+		for i, aggregate := range instrumentConfig.Aggregates {
+			if aggregate.Table == table {
+				retention = &instrumentConfig.Aggregates[i].Retention
+			}
+		}
+	}
+	if retention == nil {
+		return errors.New(fmt.Sprintf(
+			"'%s' is neither the base table nor an aggregate of %s",
+			table,
+			instrumentType,
+		))
+	}
+
+	// Aggregates can be the source of other aggregates, so they are guarded
+	// like the base table
+	var aggregates []storage.Aggregate
+	for _, aggregate := range instrumentConfig.Aggregates {
+		aggregates = append(aggregates, storage.Aggregate{
+			Table:    aggregate.Table,
+			Interval: aggregate.Interval(),
+		})
+	}
+
+	err = storage.CheckDownsampled(table, dropAfter, aggregates)
+	if err != nil {
+		return err
+	}
+
+	data := SqlTemplateData{
+		ReferenceTable: table,
+		DropAfter:      dropAfter,
+	}
+
+	if *retention == nil {
+		up, err := renderTemplate("add_retention_policy.up.template.sql", data)
+		if err != nil {
+			return err
+		}
+		down, err := renderTemplate("add_retention_policy.down.template.sql", data)
+		if err != nil {
+			return err
+		}
+
+		err = createRawMigration("add_retention_policy", up, down)
+		if err != nil {
+			return err
+		}
+	} else if (*retention).DropAfter != dropAfter {
+		up, err := renderTemplate("update_retention_policy.up.template.sql", data)
+		if err != nil {
+			return err
+		}
+
+		data.DropAfter = (*retention).DropAfter
+		down, err := renderTemplate(
+			"update_retention_policy.down.template.sql",
+			data,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = createRawMigration("update_retention_policy", up, down)
+		if err != nil {
+			return err
+		}
+	} else {
+		return nil
+	}
+
+	err = Apply()
+	if err != nil {
+		return err
+	}
+
+	*retention = &config.RetentionConfig{DropAfter: dropAfter}
+	return config.UpdateConfig(instrumentType, &instrumentConfig)
+}
@@ -0,0 +1,281 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/premia-ai/cli/internal/aggregates"
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/helper"
+)
+
+type templateMigration struct {
+	Name string
+	// Object is the table or view that the migration creates
+	Object string
+	Data   SqlTemplateData
+}
+
+// WidenVolume creates and applies a migration that changes the volume column
+// of the instrument's base table to the volume type. Views that depend on the
+// column are dropped beforehand and recreated afterwards since Postgres
+// doesn't allow changing the type of columns that are used by views.
+// Continuous aggregates keep their policies and are refreshed completely
+// afterwards.
+func WidenVolume(
+	instrumentType config.InstrumentType,
+	volumeType string,
+) error {
+	if !helper.IsInSlice(config.VolumeTypes, volumeType) {
+		return errors.New(fmt.Sprintf(
+			"Volume type '%s' is not supported, please use one of: %s",
+			volumeType,
+			strings.Join(config.VolumeTypes, ", "),
+		))
+	}
+
+	configData, err := config.Config()
+	if err != nil {
+		return err
+	}
+
+	instrumentConfig, ok := configData.Instruments[instrumentType]
+	if !ok {
+		return errors.New(
+			fmt.Sprintf("There is no '%s' table set up.", instrumentType),
+		)
+	}
+
+	previousVolumeType := instrumentConfig.VolumeType
+	if previousVolumeType == "" {
+		previousVolumeType = config.VolumeInt
+	}
+	if previousVolumeType == volumeType {
+		return errors.New(fmt.Sprintf(
+			"The volume of '%s' is already stored as %s.",
+			instrumentConfig.BaseTable,
+			volumeType,
+		))
+	}
+
+	var dependents []schemaDependent
+	if configData.DatabaseBackend() == config.BackendDuckDB {
+		dependents, err = configDependents(configData, instrumentType)
+	} else {
+		dependents, err = catalogDependents(
+			instrumentConfig.BaseTable,
+			configData.DatabaseBackend() == config.BackendTimescale,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	up, err := renderSchemaUpgrade(
+		dependents,
+		"widen_volume.up.template.sql",
+		SqlTemplateData{
+			ReferenceTable: instrumentConfig.BaseTable,
+			VolumeType:     volumeType,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	down, err := renderSchemaUpgrade(
+		dependents,
+		"widen_volume.down.template.sql",
+		SqlTemplateData{
+			ReferenceTable: instrumentConfig.BaseTable,
+			VolumeType:     previousVolumeType,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = createRawMigration("widen_volume", up, down)
+	if err != nil {
+		return err
+	}
+
+	err = Apply()
+	if err != nil {
+		return err
+	}
+
+	// The recreated continuous aggregates are empty until they are refreshed
+	var continuousAggregates []string
+	for _, dependent := range dependents {
+		if dependent.continuous {
+			continuousAggregates = append(continuousAggregates, dependent.name)
+		}
+	}
+	err = aggregates.RefreshAll(continuousAggregates)
+	if err != nil {
+		return err
+	}
+
+	instrumentConfig.VolumeType = volumeType
+	return config.UpdateConfig(instrumentType, &instrumentConfig)
+}
+
+// dependentViews returns the migrations of all views that are based on the
+// instrument's base table in the order they need to be created.
+func dependentViews(
+	configData *config.ConfigFileData,
+	instrumentType config.InstrumentType,
+) ([]templateMigration, error) {
+	instrumentConfig := configData.Instruments[instrumentType]
+
+	var views []templateMigration
+	for _, aggregate := range instrumentConfig.Aggregates {
+		data, err := aggregateTemplateData(
+			instrumentType,
+			&instrumentConfig,
+			&aggregate,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		views = append(views, templateMigration{
+			Name:   "add_aggregate_candles",
+			Object: aggregate.Table,
+			Data:   data,
+		})
+	}
+
+	if instrumentConfig.AdjustedTable != "" {
+		views = append(views, templateMigration{
+			Name:   "add_adjusted_candles",
+			Object: instrumentConfig.AdjustedTable,
+			Data:   SqlTemplateData{ReferenceTable: instrumentConfig.BaseTable},
+		})
+	}
+
+	for _, table := range []string{
+		instrumentConfig.BaseTable,
+		instrumentConfig.AdjustedTable,
+	} {
+		convertedTable := fmt.Sprintf(
+			"%s_%s",
+			table,
+			strings.ToLower(configData.ReportingCurrency),
+		)
+		if table == "" ||
+			!helper.IsInSlice(instrumentConfig.ConvertedTables, convertedTable) {
+			continue
+		}
+
+		views = append(views, templateMigration{
+			Name:   "add_converted_candles",
+			Object: convertedTable,
+			Data: SqlTemplateData{
+				ReferenceTable: table,
+				Currency:       configData.ReportingCurrency,
+			},
+		})
+	}
+
+	timezone, err := exchangeTimezone(instrumentConfig.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, feature := range instrumentConfig.Features {
+		views = append(views, templateMigration{
+			Name:   feature.Name,
+			Object: feature.View(instrumentType),
+			Data: SqlTemplateData{
+				InstrumentType: instrumentType,
+				Quantity:       feature.Quantity,
+				TimeUnit:       feature.TimespanUnit,
+				ReferenceTable: feature.ReferenceTable,
+				Exchange:       instrumentConfig.Exchange,
+				Timezone:       timezone,
+				Window:         feature.WindowSize(),
+				View:           feature.View(instrumentType),
+			},
+		})
+	}
+
+	return views, nil
+}
+
+// renderSchemaUpgrade wraps the upgrade template between dropping and
+// recreating the dependent views.
+func renderSchemaUpgrade(
+	dependents []schemaDependent,
+	templateName string,
+	data SqlTemplateData,
+) (string, error) {
+	var parts []string
+	for i := len(dependents) - 1; i >= 0; i-- {
+		parts = append(parts, dependents[i].drop)
+	}
+
+	part, err := renderTemplate(templateName, data)
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, part)
+
+	for _, dependent := range dependents {
+		parts = append(parts, dependent.create)
+	}
+
+	return strings.Join(parts, "\n"), nil
+}
+
+// createRawMigration adds a migration with the next free version after the
+// existing migrations.
+func createRawMigration(name, up, down string) error {
+	version, err := nextMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	err = writeMigration(fmt.Sprintf("%d_%s.up.sql", version, name), up)
+	if err != nil {
+		return err
+	}
+
+	return writeMigration(fmt.Sprintf("%d_%s.down.sql", version, name), down)
+}
+
+func nextMigrationVersion() (int, error) {
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	maxVersion := 0
+	for _, entry := range entries {
+		version, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			continue
+		}
+		maxVersion = max(maxVersion, version)
+	}
+
+	return maxVersion + 1, nil
+}
+
+func Apply() error {
+	migrationsDir, err := config.MigrationsDir(false)
+	if err != nil {
+		return err
+	}
+
+	return applyMigrations(migrationsDir)
+}
@@ -0,0 +1,182 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+)
+
+const (
+	KindBase      = "base"
+	KindAdjusted  = "adjusted"
+	KindConverted = "converted"
+	KindAggregate = "aggregate"
+	KindFeature   = "feature"
+)
+
+type Report struct {
+	// Symbol is set if the report only covers the rows of one symbol
+	Symbol      string       `json:"symbol,omitempty"`
+	Instruments []Instrument `json:"instruments"`
+}
+
+type Instrument struct {
+	Type   string  `json:"type"`
+	Tables []Table `json:"tables"`
+}
+
+type Table struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Exists bool   `json:"exists"`
+	// NotPopulated is set for materialized views of the postgres backend
+	// that have never been refreshed, their rows aren't counted
+	NotPopulated bool  `json:"notPopulated,omitempty"`
+	Rows         int64 `json:"rows"`
+	Symbols      int64 `json:"symbols"`
+	// From and To are nil if the table is empty or doesn't exist
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// Collect counts the rows, symbols and the covered time range of every table
+// and view that the config references. If symbol is set only its rows are
+// counted.
+func Collect(
+	configData *config.ConfigFileData,
+	symbol string,
+) (*Report, error) {
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Symbol: symbol, Instruments: []Instrument{}}
+
+	var instrumentTypes []string
+	for instrumentType := range configData.Instruments {
+		instrumentTypes = append(instrumentTypes, string(instrumentType))
+	}
+	sort.Strings(instrumentTypes)
+
+	for _, instrumentType := range instrumentTypes {
+		instrumentConfig := configData.Instruments[config.InstrumentType(instrumentType)]
+		instrument := Instrument{Type: instrumentType}
+
+		for _, table := range tables(config.InstrumentType(instrumentType), instrumentConfig) {
+			exists, err := db.TableExists(context.Background(), table.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			table.Exists = exists
+			if exists && db.Backend() == config.BackendPostgres {
+				table.NotPopulated, err = notPopulated(db, table.Name)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if exists && !table.NotPopulated {
+				err = count(db, &table, symbol)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			instrument.Tables = append(instrument.Tables, table)
+		}
+
+		report.Instruments = append(report.Instruments, instrument)
+	}
+
+	return report, nil
+}
+
+// tables returns the tables of the instrument in the order of
+// InstrumentConfig.Tables.
+func tables(
+	instrumentType config.InstrumentType,
+	instrumentConfig config.InstrumentConfig,
+) []Table {
+	tables := []Table{{Name: instrumentConfig.BaseTable, Kind: KindBase}}
+	if instrumentConfig.AdjustedTable != "" {
+		tables = append(tables, Table{
+			Name: instrumentConfig.AdjustedTable,
+			Kind: KindAdjusted,
+		})
+	}
+	for _, convertedTable := range instrumentConfig.ConvertedTables {
+		tables = append(tables, Table{Name: convertedTable, Kind: KindConverted})
+	}
+	for _, aggregate := range instrumentConfig.Aggregates {
+		tables = append(tables, Table{Name: aggregate.Table, Kind: KindAggregate})
+	}
+	for _, feature := range instrumentConfig.Features {
+		tables = append(tables, Table{
+			Name: feature.View(instrumentType),
+			Kind: KindFeature,
+		})
+	}
+
+	return tables
+}
+
+// notPopulated reports whether the table is a materialized view that can't be
+// queried until it's refreshed.
+func notPopulated(db database.DB, table string) (bool, error) {
+	var notPopulated bool
+	err := db.QueryRow(
+		context.Background(),
+		`SELECT EXISTS (
+			SELECT 1 FROM pg_matviews
+			WHERE schemaname = current_schema()
+				AND matviewname = $1
+				AND NOT ispopulated
+		);`,
+		table,
+	).Scan(&notPopulated)
+	return notPopulated, err
+}
+
+func count(db database.DB, table *Table, symbol string) error {
+	// Aggregates name their time column after the bucket
+	timeColumn := "time"
+	if table.Kind == KindAggregate {
+		timeColumn = "bucket"
+	}
+
+	query := fmt.Sprintf(
+		"SELECT COUNT(*), COUNT(DISTINCT symbol), MIN(%s), MAX(%s) FROM %s",
+		timeColumn,
+		timeColumn,
+		pgx.Identifier{table.Name}.Sanitize(),
+	)
+	var args []any
+	if symbol != "" {
+		query += " WHERE symbol = $1"
+		args = append(args, symbol)
+	}
+
+	err := db.QueryRow(context.Background(), query, args...).Scan(
+		&table.Rows,
+		&table.Symbols,
+		&table.From,
+		&table.To,
+	)
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"Counting the rows of '%s' failed: %v",
+			table.Name,
+			err,
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/premia-ai/cli/internal/database"
+)
+
+// Aggregate is a continuous aggregate whose buckets have the width of the
+// interval, e.g. "1 day".
+type Aggregate struct {
+	Table    string
+	Interval string
+}
+
+type continuousAggregate struct {
+	view string
+	// source is the hypertable or the continuous aggregate that the
+	// aggregate is built on
+	source                string
+	materializationSchema string
+	materializationTable  string
+	hasRefreshPolicy      bool
+	// startOffset is empty if the refresh policy has no start, i.e. it
+	// refreshes everything
+	startOffset string
+	// refreshedBeforeDrop is set if the refresh window ends before data
+	// that is older than the retention interval gets dropped
+	refreshedBeforeDrop bool
+}
+
+// CheckDownsampled returns an error if dropping the data of the table that
+// is older than dropAfter would lose data that isn't kept in a downsampled
+// form:
+//   - the refresh window of every continuous aggregate that is built on the
+//     table has to end before the retention interval, otherwise refreshing it
+//     erases the buckets of the dropped data
+//   - the base table and aggregates that other aggregates are built on need
+//     one aggregate whose refresh policy materializes the data before it's
+//     dropped and whose buckets cover the data that would be dropped now
+//   - the refresh window of an aggregate itself has to end before its
+//     retention interval, otherwise dropped buckets get materialized again
+//
+// The aggregates of the config provide the bucket widths, aggregates are
+// resolved from the database so that hierarchical aggregates and aggregates
+// that are missing in the config are considered too.
+func CheckDownsampled(
+	table string,
+	dropAfter string,
+	aggregates []Aggregate,
+) error {
+	pool, err := database.Pool()
+	if err != nil {
+		return err
+	}
+
+	continuousAggregates, err := loadContinuousAggregates(pool, dropAfter)
+	if err != nil {
+		return err
+	}
+
+	intervals := make(map[string]string)
+	for _, aggregate := range aggregates {
+		intervals[aggregate.Table] = aggregate.Interval
+	}
+
+	var problems []string
+
+	self, isAggregate := continuousAggregates[table]
+	if isAggregate && self.hasRefreshPolicy && !self.refreshedBeforeDrop {
+		problems = append(problems, fmt.Sprintf(
+			"its refresh policy %s, which would materialize the dropped buckets again",
+			describeRefreshWindow(self),
+		))
+	}
+
+	var children, covering []*continuousAggregate
+	for _, aggregate := range continuousAggregates {
+		if aggregate.source != table {
+			continue
+		}
+		children = append(children, aggregate)
+
+		if !aggregate.hasRefreshPolicy {
+			continue
+		}
+		if !aggregate.refreshedBeforeDrop {
+			problems = append(problems, fmt.Sprintf(
+				"the refresh policy of '%s' %s, which would erase its buckets of the dropped data",
+				aggregate.view,
+				describeRefreshWindow(aggregate),
+			))
+			continue
+		}
+		covering = append(covering, aggregate)
+	}
+
+	// Leaf aggregates may expire their buckets without downsampling them
+	// any further
+	if isAggregate && len(children) == 0 {
+		return refuseRetention(table, dropAfter, problems)
+	}
+
+	if len(covering) == 0 {
+		problems = append(problems, fmt.Sprintf(
+			"there is no continuous aggregate based on it with a refresh policy whose start offset is shorter than %s",
+			dropAfter,
+		))
+		return refuseRetention(table, dropAfter, problems)
+	}
+
+	var firstDropped, lastDropped *time.Time
+	err = pool.QueryRow(
+		context.Background(),
+		fmt.Sprintf(
+			"SELECT min(%s), max(%s) FROM %s WHERE %s < now() - $1::interval;",
+			timeColumn(isAggregate),
+			timeColumn(isAggregate),
+			pgx.Identifier{table}.Sanitize(),
+			timeColumn(isAggregate),
+		),
+		dropAfter,
+	).Scan(&firstDropped, &lastDropped)
+	if err != nil {
+		return err
+	}
+
+	// Nothing would be dropped right now, the covering aggregates materialize
+	// the data before it's dropped from here on
+	if firstDropped == nil {
+		return refuseRetention(table, dropAfter, problems)
+	}
+
+	var coverageProblems []string
+	covered := false
+	for _, aggregate := range covering {
+		interval, ok := intervals[aggregate.view]
+		if !ok {
+			coverageProblems = append(coverageProblems, fmt.Sprintf(
+				"'%s' is not in the config, so its coverage cannot be checked",
+				aggregate.view,
+			))
+			continue
+		}
+
+		// The materialization table is checked instead of the aggregate
+		// itself since real-time aggregates also return unmaterialized data
+		// which would be dropped together with the raw data
+		var aggregateCovered bool
+		err = pool.QueryRow(
+			context.Background(),
+			fmt.Sprintf(
+				`SELECT COALESCE(min(bucket) <= $1 AND max(bucket) + $3::interval > $2, FALSE)
+				FROM %s;`,
+				pgx.Identifier{
+					aggregate.materializationSchema,
+					aggregate.materializationTable,
+				}.Sanitize(),
+			),
+			*firstDropped,
+			*lastDropped,
+			interval,
+		).Scan(&aggregateCovered)
+		if err != nil {
+			return err
+		}
+
+		if aggregateCovered {
+			covered = true
+			break
+		}
+
+		coverageProblems = append(coverageProblems, fmt.Sprintf(
+			"'%s' has not been refreshed for the period from %s to %s",
+			aggregate.view,
+			firstDropped.Format(time.RFC3339),
+			lastDropped.Format(time.RFC3339),
+		))
+	}
+	if !covered {
+		problems = append(problems, coverageProblems...)
+	}
+
+	return refuseRetention(table, dropAfter, problems)
+}
+
+// loadContinuousAggregates returns the continuous aggregates of the database
+// by their view names with their sources resolved to view names for
+// hierarchical aggregates.
+func loadContinuousAggregates(
+	pool *pgxpool.Pool,
+	dropAfter string,
+) (map[string]*continuousAggregate, error) {
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT
+			ca.view_name,
+			ca.hypertable_name,
+			ca.materialization_hypertable_schema,
+			ca.materialization_hypertable_name,
+			j.job_id IS NOT NULL,
+			COALESCE(j.config->>'start_offset', ''),
+			COALESCE($1::interval > (j.config->>'start_offset')::interval, FALSE)
+		FROM timescaledb_information.continuous_aggregates ca
+		LEFT JOIN timescaledb_information.jobs j
+			ON j.proc_name = 'policy_refresh_continuous_aggregate'
+			AND j.hypertable_schema = ca.materialization_hypertable_schema
+			AND j.hypertable_name = ca.materialization_hypertable_name;`,
+		dropAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := make(map[string]*continuousAggregate)
+	views := make(map[string]string)
+	for rows.Next() {
+		var aggregate continuousAggregate
+		err = rows.Scan(
+			&aggregate.view,
+			&aggregate.source,
+			&aggregate.materializationSchema,
+			&aggregate.materializationTable,
+			&aggregate.hasRefreshPolicy,
+			&aggregate.startOffset,
+			&aggregate.refreshedBeforeDrop,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregates[aggregate.view] = &aggregate
+		views[aggregate.materializationTable] = aggregate.view
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// Aggregates on top of other aggregates reference the materialization
+	// hypertable of their source
+	for _, aggregate := range aggregates {
+		if view, ok := views[aggregate.source]; ok {
+			aggregate.source = view
+		}
+	}
+
+	return aggregates, nil
+}
+
+func describeRefreshWindow(aggregate *continuousAggregate) string {
+	if aggregate.startOffset == "" {
+		return "refreshes all buckets"
+	}
+
+	return fmt.Sprintf("refreshes the last %s", aggregate.startOffset)
+}
+
+// timeColumn returns the time column of the table, aggregates name it after
+// the bucket.
+func timeColumn(isAggregate bool) string {
+	if isAggregate {
+		return "bucket"
+	}
+	return "time"
+}
+
+func refuseRetention(table, dropAfter string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf(
+		"Refusing to drop data of '%s' older than %s:",
+		table,
+		dropAfter,
+	)
+	for _, problem := range problems {
+		message += "\n - " + problem
+	}
+	return errors.New(message)
+}
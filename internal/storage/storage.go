@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/premia-ai/cli/internal/config"
+	"github.com/premia-ai/cli/internal/database"
+	"github.com/premia-ai/cli/internal/dataprovider"
+)
+
+type ChunkSize struct {
+	Chunk       string
+	BeforeBytes int64
+	AfterBytes  int64
+}
+
+// TableSize returns the size of a hypertable including all of its chunks and
+// indexes.
+func TableSize(table string) (int64, error) {
+	pool, err := database.Pool()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	err = pool.QueryRow(
+		context.Background(),
+		"SELECT COALESCE(hypertable_size($1), 0);",
+		table,
+	).Scan(&size)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// CompressChunks compresses all chunks of the table that are older than the
+// interval right away instead of waiting for the compression policy and
+// returns the number of compressed chunks.
+func CompressChunks(table, olderThan string) (int, error) {
+	pool, err := database.Pool()
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT compress_chunk(chunk, if_not_compressed => true)
+		FROM show_chunks($1::regclass, older_than => $2::interval) AS chunk;`,
+		table,
+		olderThan,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count += 1
+	}
+
+	return count, rows.Err()
+}
+
+// CompressedChunkSizes returns the sizes of the table's compressed chunks
+// before and after their compression.
+func CompressedChunkSizes(table string) ([]ChunkSize, error) {
+	pool, err := database.Pool()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(
+		context.Background(),
+		`SELECT
+			chunk_schema || '.' || chunk_name,
+			before_compression_total_bytes,
+			after_compression_total_bytes
+		FROM chunk_compression_stats($1::regclass)
+		WHERE compression_status = 'Compressed'
+		ORDER BY chunk_name;`,
+		table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sizes []ChunkSize
+	for rows.Next() {
+		var size ChunkSize
+		err = rows.Scan(&size.Chunk, &size.BeforeBytes, &size.AfterBytes)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+
+	return sizes, rows.Err()
+}
+
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "kMGTPE"[exp])
+}
+
+// ConfiguredTables returns the tables and views that the config references,
+// dependent views come after the tables they are based on.
+func ConfiguredTables(configData *config.ConfigFileData) []string {
+	var tables []string
+	if configData.ReportingCurrency != "" {
+		tables = append(tables, dataprovider.FxRatesTable)
+	}
+
+	var instrumentTypes []string
+	for instrumentType := range configData.Instruments {
+		instrumentTypes = append(instrumentTypes, string(instrumentType))
+	}
+	sort.Strings(instrumentTypes)
+	for _, instrumentType := range instrumentTypes {
+		instrumentConfig := configData.Instruments[config.InstrumentType(instrumentType)]
+		tables = append(
+			tables,
+			instrumentConfig.Tables(config.InstrumentType(instrumentType))...,
+		)
+	}
+
+	return tables
+}
+
+// MissingTables returns the tables and views that don't exist in the database.
+func MissingTables(tables []string) ([]string, error) {
+	db, err := database.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, table := range tables {
+		exists, err := db.TableExists(context.Background(), table)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+
+	return missing, nil
+}
@@ -4,7 +4,13 @@ import (
 	"embed"
 )
 
-//go:embed templates
+//go:embed templates calendars schemas
 var Fs embed.FS
 
+const TemplatesPath = "templates"
+
 const TemplateFeaturesPath = "templates/features"
+
+const CalendarsPath = "calendars"
+
+const ConfigSchemaPath = "schemas/config.schema.json"
@@ -0,0 +1,16 @@
+-- DuckDB computes the view on every query, so it's always up to date and
+-- doesn't need to be refreshed
+CREATE OR REPLACE VIEW {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles AS
+    SELECT
+        time_bucket(INTERVAL '{{ .BucketInterval }}', {{ .TimeColumn }}{{ if .BucketTimezone }}, '{{ .BucketTimezone }}'{{ end }}) AS bucket,
+        symbol,
+        arg_min(open, {{ .TimeColumn }}) AS "open",
+        MAX(high) AS high,
+        MIN(low) AS low,
+        arg_max(close, {{ .TimeColumn }}) AS "close",
+        SUM(volume) AS volume,
+        currency
+    FROM {{ .ReferenceTable }}
+    -- The position is used since "bucket" would refer to the source column of
+    -- aggregates that are based on other aggregates
+    GROUP BY 1, currency, symbol;
@@ -0,0 +1,16 @@
+-- DuckDB's NUMERIC only keeps three decimal places, prices use DECIMAL(38, 10)
+-- instead
+CREATE TABLE IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles (
+    time TIMESTAMPTZ NOT NULL,
+    symbol TEXT NOT NULL,
+    open DECIMAL(38, 10) NULL,
+    close DECIMAL(38, 10) NULL,
+    high DECIMAL(38, 10) NULL,
+    low DECIMAL(38, 10) NULL,
+    volume {{ if eq .VolumeType "NUMERIC" }}DECIMAL(38, 10){{ else }}{{ .VolumeType }}{{ end }} NULL,
+    currency TEXT NOT NULL,
+    data_provider TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles_symbol_time_idx
+ON {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles (symbol, time);
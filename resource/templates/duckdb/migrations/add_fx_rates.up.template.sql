@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS fx_rates (
+    time TIMESTAMPTZ NOT NULL,
+    base_currency TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate DECIMAL(38, 10) NOT NULL,
+    data_provider TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS fx_rates_currencies_time_idx
+ON fx_rates (base_currency, quote_currency, time);
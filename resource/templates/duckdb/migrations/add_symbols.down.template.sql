@@ -0,0 +1,2 @@
+DROP MACRO IF EXISTS trading_date;
+DROP TABLE IF EXISTS symbols;
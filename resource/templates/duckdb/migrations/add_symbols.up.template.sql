@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS symbols (
+    symbol TEXT PRIMARY KEY,
+    exchange TEXT,
+    exchange_timezone TEXT NOT NULL,
+    data_provider TEXT NOT NULL
+);
+
+-- Returns the trading date of a point in time in the local time of the
+-- symbol's exchange, symbols without a known exchange use the default timezone.
+CREATE OR REPLACE MACRO trading_date(symbol_code, t, default_timezone) AS (
+    timezone(
+        COALESCE(
+            (SELECT exchange_timezone FROM symbols WHERE symbol = symbol_code),
+            default_timezone
+        ),
+        t::TIMESTAMPTZ
+    )::date
+);
@@ -0,0 +1,2 @@
+DROP MACRO IF EXISTS market_session;
+DROP TABLE IF EXISTS trading_calendars;
@@ -0,0 +1,46 @@
+-- DuckDB needs the seconds in time literals
+CREATE TABLE IF NOT EXISTS trading_calendars (
+    exchange TEXT NOT NULL,
+    date DATE NOT NULL,
+    timezone TEXT NOT NULL,
+    open TIMESTAMPTZ NOT NULL,
+    close TIMESTAMPTZ NOT NULL,
+    early_close BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (exchange, date)
+);
+{{ range .Calendars }}
+INSERT INTO trading_calendars (exchange, date, timezone, open, close, early_close)
+SELECT
+    '{{ .Exchange }}',
+    day::date,
+    '{{ .Timezone }}',
+    timezone('{{ .Timezone }}', day::date + TIME '{{ .Open }}:00'),
+    CASE
+        WHEN list_contains({{ sqlDates .EarlyCloses }}, day::date)
+        THEN timezone('{{ .Timezone }}', day::date + TIME '{{ or .EarlyClose .Close }}:00')
+        ELSE timezone('{{ .Timezone }}', day::date + TIME '{{ .Close }}:00')
+    END,
+    list_contains({{ sqlDates .EarlyCloses }}, day::date)
+FROM generate_series(DATE '{{ .From }}', DATE '{{ .To }}', INTERVAL '1 day') AS days(day)
+WHERE isodow(day) < 6
+    AND NOT list_contains({{ sqlDates .Holidays }}, day::date)
+ON CONFLICT DO NOTHING;
+{{ end }}
+-- Classifies a point in time as 'pre', 'regular' or 'post' market session of
+-- the exchange's trading day, days without trading are 'closed'.
+CREATE OR REPLACE MACRO market_session(exchange_code, t) AS (
+    COALESCE(
+        (
+            SELECT
+                CASE
+                    WHEN t < open THEN 'pre'
+                    WHEN t < close THEN 'regular'
+                    ELSE 'post'
+                END
+            FROM trading_calendars
+            WHERE exchange = exchange_code
+                AND date = timezone(timezone, t::TIMESTAMPTZ)::date
+        ),
+        'closed'
+    )
+);
@@ -0,0 +1,7 @@
+-- DuckDB cannot alter columns of tables with indexes
+DROP INDEX IF EXISTS {{ .ReferenceTable }}_symbol_time_idx;
+
+ALTER TABLE {{ .ReferenceTable }} ALTER COLUMN volume TYPE {{ if eq .VolumeType "NUMERIC" }}DECIMAL(38, 10){{ else }}{{ .VolumeType }}{{ end }};
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{ .ReferenceTable }}_symbol_time_idx
+ON {{ .ReferenceTable }} (symbol, time);
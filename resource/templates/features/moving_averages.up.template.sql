@@ -1,4 +1,4 @@
-CREATE OR REPLACE VIEW {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_averages AS
+CREATE OR REPLACE VIEW {{ .View }} AS
 SELECT time, symbol, average
 FROM (
      SELECT
@@ -7,13 +7,13 @@ FROM (
         AVG(close) OVER (
             PARTITION BY symbol 
             ORDER BY time 
-            ROWS BETWEEN {{ sub .Quantity 1 }} PRECEDING AND CURRENT ROW
+            ROWS BETWEEN {{ sub .Window 1 }} PRECEDING AND CURRENT ROW
         ) AS average,
         COUNT(close) OVER (
             PARTITION BY symbol 
             ORDER BY time 
-            ROWS BETWEEN {{ sub .Quantity 1 }} PRECEDING AND CURRENT ROW
+            ROWS BETWEEN {{ sub .Window 1 }} PRECEDING AND CURRENT ROW
         ) AS row_count
     FROM {{ .ReferenceTable }}
 )
-WHERE row_count = {{ .Quantity }};
+WHERE row_count = {{ .Window }};
@@ -0,0 +1 @@
+DROP VIEW IF EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_sessions;
@@ -0,0 +1,7 @@
+CREATE OR REPLACE VIEW {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_sessions AS
+SELECT
+    "time",
+    symbol,
+    market_session('{{ .Exchange }}', "time") AS session,
+    trading_date(symbol, "time", '{{ .Timezone }}') AS trading_date
+FROM {{ .ReferenceTable }};
@@ -0,0 +1 @@
+DROP VIEW IF EXISTS {{ .ReferenceTable }}_adjusted;
@@ -0,0 +1,42 @@
+-- Back-adjusts prices for all splits and dividends that happened after a
+-- candle. Dividends are adjusted by the ratio of the dividend to the last close
+-- before the ex-dividend date.
+CREATE OR REPLACE VIEW {{ .ReferenceTable }}_adjusted AS
+WITH dividend_factors AS (
+    SELECT
+        dividends.symbol,
+        dividends.ex_dividend_date,
+        1 - dividends.cash_amount / previous.close AS factor
+    FROM dividends
+    JOIN LATERAL (
+        SELECT close
+        FROM {{ .ReferenceTable }}
+        WHERE symbol = dividends.symbol
+            AND "time" < dividends.ex_dividend_date
+        ORDER BY "time" DESC
+        LIMIT 1
+    ) previous ON previous.close > dividends.cash_amount
+)
+SELECT
+    candles."time",
+    candles.symbol,
+    candles.open * split_adjustment.factor * dividend_adjustment.factor AS open,
+    candles.close * split_adjustment.factor * dividend_adjustment.factor AS close,
+    candles.high * split_adjustment.factor * dividend_adjustment.factor AS high,
+    candles.low * split_adjustment.factor * dividend_adjustment.factor AS low,
+    candles.volume / split_adjustment.factor AS volume,
+    candles.currency,
+    candles.data_provider
+FROM {{ .ReferenceTable }} candles
+CROSS JOIN LATERAL (
+    SELECT COALESCE(EXP(SUM(LN(split_from / split_to))), 1) AS factor
+    FROM splits
+    WHERE splits.symbol = candles.symbol
+        AND splits.execution_date > candles."time"
+) split_adjustment
+CROSS JOIN LATERAL (
+    SELECT COALESCE(EXP(SUM(LN(factor))), 1) AS factor
+    FROM dividend_factors
+    WHERE dividend_factors.symbol = candles.symbol
+        AND dividend_factors.ex_dividend_date > candles."time"
+) dividend_adjustment;
@@ -1,12 +1,12 @@
 CREATE MATERIALIZED VIEW {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles
 WITH (timescaledb.continuous) AS
     SELECT
-        time_bucket('{{ .Quantity }} {{ .TimeUnit }}', time) AS bucket,
+        time_bucket('{{ .BucketInterval }}', {{ .TimeColumn }}{{ if .BucketTimezone }}, '{{ .BucketTimezone }}'{{ end }}) AS bucket,
         symbol,
-        FIRST(open, time) AS "open",
+        FIRST(open, {{ .TimeColumn }}) AS "open",
         MAX(high) AS high,
         MIN(low) AS low,
-        LAST(close, time) AS "close",
+        LAST(close, {{ .TimeColumn }}) AS "close",
         SUM(volume) AS volume,
         currency
     FROM {{ .ReferenceTable }}
@@ -14,6 +14,6 @@ WITH (timescaledb.continuous) AS
 WITH NO DATA;
 
 SELECT add_continuous_aggregate_policy('{{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles',
-    start_offset => INTERVAL '3 day',
-    end_offset => INTERVAL '1 day',
-    schedule_interval => INTERVAL '1 day');
+    start_offset => INTERVAL '{{ .Refresh.StartOffset }}',
+    end_offset => INTERVAL '{{ .Refresh.EndOffset }}',
+    schedule_interval => INTERVAL '{{ .Refresh.ScheduleInterval }}');
@@ -5,7 +5,7 @@ CREATE TABLE IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}
     close NUMERIC NULL,
     high NUMERIC NULL,
     low NUMERIC NULL,
-    volume INT NULL,
+    volume {{ .VolumeType }} NULL,
     currency TEXT NOT NULL,
     data_provider TEXT NOT NULL
 );
@@ -0,0 +1,6 @@
+SELECT remove_compression_policy('{{ .ReferenceTable }}', if_exists => true);
+
+SELECT decompress_chunk(chunk, if_compressed => true)
+FROM show_chunks('{{ .ReferenceTable }}') AS chunk;
+
+ALTER TABLE {{ .ReferenceTable }} SET (timescaledb.compress = false);
@@ -0,0 +1,7 @@
+ALTER TABLE {{ .ReferenceTable }} SET (
+    timescaledb.compress,
+    timescaledb.compress_segmentby = 'symbol',
+    timescaledb.compress_orderby = 'time DESC'
+);
+
+SELECT add_compression_policy('{{ .ReferenceTable }}', INTERVAL '{{ .CompressAfter }}');
@@ -0,0 +1,37 @@
+-- Converts prices with the latest rate at the time of the candle. Pairs that
+-- are only stored in the opposite direction are inverted.
+CREATE OR REPLACE VIEW {{ .ReferenceTable }}_{{ lower .Currency }} AS
+SELECT
+    candles."time",
+    candles.symbol,
+    candles.open * fx.rate AS open,
+    candles.close * fx.rate AS close,
+    candles.high * fx.rate AS high,
+    candles.low * fx.rate AS low,
+    candles.volume,
+    '{{ .Currency }}' AS currency,
+    candles.data_provider
+FROM {{ .ReferenceTable }} candles
+CROSS JOIN LATERAL (
+    SELECT COALESCE(
+        CASE WHEN candles.currency = '{{ .Currency }}' THEN 1 END,
+        (
+            SELECT rate
+            FROM fx_rates
+            WHERE base_currency = candles.currency
+                AND quote_currency = '{{ .Currency }}'
+                AND "time" <= candles."time"
+            ORDER BY "time" DESC
+            LIMIT 1
+        ),
+        (
+            SELECT 1 / rate
+            FROM fx_rates
+            WHERE base_currency = '{{ .Currency }}'
+                AND quote_currency = candles.currency
+                AND "time" <= candles."time"
+            ORDER BY "time" DESC
+            LIMIT 1
+        )
+    ) AS rate
+) fx;
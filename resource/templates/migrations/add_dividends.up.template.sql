@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS dividends (
+    symbol TEXT NOT NULL,
+    ex_dividend_date DATE NOT NULL,
+    cash_amount NUMERIC NOT NULL,
+    dividend_type TEXT NOT NULL,
+    frequency INT NULL,
+    declaration_date DATE NULL,
+    record_date DATE NULL,
+    pay_date DATE NULL,
+    data_provider TEXT NOT NULL,
+    PRIMARY KEY (symbol, ex_dividend_date, dividend_type)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS fx_rates_currencies_time_idx;
+DROP TABLE IF EXISTS fx_rates;
@@ -0,0 +1 @@
+SELECT remove_retention_policy('{{ .ReferenceTable }}', if_exists => true);
@@ -0,0 +1 @@
+SELECT add_retention_policy('{{ .ReferenceTable }}', drop_after => INTERVAL '{{ .DropAfter }}');
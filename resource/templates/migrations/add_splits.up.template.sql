@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS splits (
+    symbol TEXT NOT NULL,
+    execution_date DATE NOT NULL,
+    split_from NUMERIC NOT NULL,
+    split_to NUMERIC NOT NULL,
+    data_provider TEXT NOT NULL,
+    PRIMARY KEY (symbol, execution_date)
+);
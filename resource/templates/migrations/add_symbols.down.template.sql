@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS trading_date(TEXT, TIMESTAMPTZ, TEXT);
+DROP TABLE IF EXISTS symbols;
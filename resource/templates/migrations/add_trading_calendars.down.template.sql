@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS market_session(TEXT, TIMESTAMPTZ);
+DROP TABLE IF EXISTS trading_calendars;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS trading_calendars (
+    exchange TEXT NOT NULL,
+    date DATE NOT NULL,
+    timezone TEXT NOT NULL,
+    open TIMESTAMPTZ NOT NULL,
+    close TIMESTAMPTZ NOT NULL,
+    early_close BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (exchange, date)
+);
+{{ range .Calendars }}
+INSERT INTO trading_calendars (exchange, date, timezone, open, close, early_close)
+SELECT
+    '{{ .Exchange }}',
+    day::date,
+    '{{ .Timezone }}',
+    (day::date + TIME '{{ .Open }}') AT TIME ZONE '{{ .Timezone }}',
+    CASE
+        WHEN day::date = ANY({{ sqlDates .EarlyCloses }})
+        THEN (day::date + TIME '{{ or .EarlyClose .Close }}') AT TIME ZONE '{{ .Timezone }}'
+        ELSE (day::date + TIME '{{ .Close }}') AT TIME ZONE '{{ .Timezone }}'
+    END,
+    day::date = ANY({{ sqlDates .EarlyCloses }})
+FROM generate_series(DATE '{{ .From }}', DATE '{{ .To }}', INTERVAL '1 day') AS day
+WHERE EXTRACT(ISODOW FROM day) < 6
+    AND NOT day::date = ANY({{ sqlDates .Holidays }})
+ON CONFLICT DO NOTHING;
+{{ end }}
+-- Classifies a point in time as 'pre', 'regular' or 'post' market session of
+-- the exchange's trading day, days without trading are 'closed'.
+CREATE OR REPLACE FUNCTION market_session(exchange_code TEXT, t TIMESTAMPTZ)
+RETURNS TEXT AS $$
+    SELECT COALESCE(
+        (
+            SELECT
+                CASE
+                    WHEN t < open THEN 'pre'
+                    WHEN t < close THEN 'regular'
+                    ELSE 'post'
+                END
+            FROM trading_calendars
+            WHERE exchange = exchange_code
+                AND date = (t AT TIME ZONE timezone)::date
+        ),
+        'closed'
+    )
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,2 @@
+SELECT remove_compression_policy('{{ .ReferenceTable }}', if_exists => true);
+SELECT add_compression_policy('{{ .ReferenceTable }}', INTERVAL '{{ .CompressAfter }}');
@@ -0,0 +1,2 @@
+SELECT remove_retention_policy('{{ .ReferenceTable }}', if_exists => true);
+SELECT add_retention_policy('{{ .ReferenceTable }}', drop_after => INTERVAL '{{ .DropAfter }}');
@@ -0,0 +1 @@
+ALTER TABLE {{ .ReferenceTable }} ALTER COLUMN volume TYPE {{ .VolumeType }};
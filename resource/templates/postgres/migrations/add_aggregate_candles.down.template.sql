@@ -0,0 +1,2 @@
+-- this also drops the view's unique index
+DROP MATERIALIZED VIEW IF EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles;
@@ -0,0 +1,34 @@
+-- Buckets are aligned like TimescaleDB's time_bucket: months are counted from
+-- January 2000 and fixed intervals from Monday, 2000-01-03. The view is filled
+-- by 'premia aggregate refresh'.
+CREATE MATERIALIZED VIEW {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles AS
+    SELECT
+        {{ if .BucketMonths -}}
+        (
+            date_trunc('month', {{ .TimeColumn }} AT TIME ZONE '{{ .BucketTimezone }}')
+            - make_interval(months => (
+                EXTRACT(YEAR FROM {{ .TimeColumn }} AT TIME ZONE '{{ .BucketTimezone }}')::int * 12
+                + EXTRACT(MONTH FROM {{ .TimeColumn }} AT TIME ZONE '{{ .BucketTimezone }}')::int - 1
+            ) % {{ .BucketMonths }})
+        ) AT TIME ZONE '{{ .BucketTimezone }}' AS bucket,
+        {{- else if .BucketTimezone -}}
+        date_bin('{{ .BucketInterval }}', {{ .TimeColumn }} AT TIME ZONE '{{ .BucketTimezone }}', TIMESTAMP '2000-01-03') AT TIME ZONE '{{ .BucketTimezone }}' AS bucket,
+        {{- else -}}
+        date_bin('{{ .BucketInterval }}', {{ .TimeColumn }}, TIMESTAMPTZ '2000-01-03 00:00:00+00') AS bucket,
+        {{- end }}
+        symbol,
+        (array_agg(open ORDER BY {{ .TimeColumn }}))[1] AS "open",
+        MAX(high) AS high,
+        MIN(low) AS low,
+        (array_agg(close ORDER BY {{ .TimeColumn }} DESC))[1] AS "close",
+        SUM(volume) AS volume,
+        currency
+    FROM {{ .ReferenceTable }}
+    -- The position is used since "bucket" would refer to the source column of
+    -- aggregates that are based on other aggregates
+    GROUP BY 1, currency, symbol
+WITH NO DATA;
+
+-- Allows refreshing the view concurrently once it has been filled
+CREATE UNIQUE INDEX {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles_symbol_bucket_idx
+ON {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles (symbol, currency, bucket);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles (
+    time TIMESTAMPTZ NOT NULL,
+    symbol TEXT NOT NULL,
+    open NUMERIC NULL,
+    close NUMERIC NULL,
+    high NUMERIC NULL,
+    low NUMERIC NULL,
+    volume {{ .VolumeType }} NULL,
+    currency TEXT NOT NULL,
+    data_provider TEXT NOT NULL
+) PARTITION BY RANGE (time);
+
+-- Yearly partitions up to five years ahead, data outside of them ends up in
+-- the default partition
+DO $$
+BEGIN
+    FOR year IN 2000..EXTRACT(YEAR FROM now())::int + 5 LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L);',
+            '{{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles_' || year,
+            '{{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles',
+            make_timestamptz(year, 1, 1, 0, 0, 0, 'UTC'),
+            make_timestamptz(year + 1, 1, 1, 0, 0, 0, 'UTC')
+        );
+    END LOOP;
+END $$;
+
+CREATE TABLE IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles_default
+PARTITION OF {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles_symbol_time_idx
+ON {{ .InstrumentType }}_{{ .Quantity }}_{{ .TimeUnit }}_candles (symbol, time DESC);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS fx_rates (
+    time TIMESTAMPTZ NOT NULL,
+    base_currency TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate NUMERIC NOT NULL,
+    data_provider TEXT NOT NULL
+) PARTITION BY RANGE (time);
+
+-- Yearly partitions up to five years ahead, data outside of them ends up in
+-- the default partition
+DO $$
+BEGIN
+    FOR year IN 2000..EXTRACT(YEAR FROM now())::int + 5 LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF fx_rates FOR VALUES FROM (%L) TO (%L);',
+            'fx_rates_' || year,
+            make_timestamptz(year, 1, 1, 0, 0, 0, 'UTC'),
+            make_timestamptz(year + 1, 1, 1, 0, 0, 0, 'UTC')
+        );
+    END LOOP;
+END $$;
+
+CREATE TABLE IF NOT EXISTS fx_rates_default PARTITION OF fx_rates DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS fx_rates_currencies_time_idx
+ON fx_rates (base_currency, quote_currency, time DESC);